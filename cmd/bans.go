@@ -0,0 +1,219 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var bansListJSON bool
+
+// bansCmd represents the bans command
+var bansCmd = &cobra.Command{
+	Use:   "bans",
+	Short: "Manage the emails & IPs banned from your Folderr instance",
+	Long: `Manage the emails, IP addresses and CIDR ranges (i.e 192.0.2.0/24) banned from your Folderr instance.
+The ban list is kept in the database's "folderrs" collection`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func normalizeBans(entries []string) ([]string, error) {
+	bans := []string{}
+	errs := []string{}
+	for _, entry := range entries {
+		ban, err := utilities.NormalizeBan(entry)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		bans = append(bans, ban)
+	}
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "\n"))
+	}
+	return bans, nil
+}
+
+// Adds or removes bans with $addToSet/$pull, so changes from others at the same time are kept
+func updateBans(cmd *cobra.Command, bans []string, add bool) error {
+	ctx := context.TODO()
+	_, client, coll, doc, err := findFolderrDocument(ctx)
+	if err != nil {
+		return err
+	}
+	defer disconnectDB(client)
+
+	existing := map[string]bool{}
+	for _, ban := range doc.Bans {
+		existing[ban] = true
+	}
+	changed := []string{}
+	for _, ban := range bans {
+		if existing[ban] != add {
+			changed = append(changed, ban)
+		}
+	}
+	verb := "Banned"
+	update := bson.D{{Key: "$addToSet", Value: bson.D{{Key: "bans", Value: bson.D{{Key: "$each", Value: bans}}}}}}
+	if !add {
+		verb = "Unbanned"
+		update = bson.D{{Key: "$pull", Value: bson.D{{Key: "bans", Value: bson.D{{Key: "$in", Value: bans}}}}}}
+	}
+	if len(changed) == 0 {
+		cmd.Println("Nothing to change")
+		return nil
+	}
+	if dry {
+		cmd.Println(verb, strings.Join(changed, ", "), "\nNOTICE: Did NOT save, due to dry run")
+		return nil
+	}
+	_, err = coll.UpdateOne(ctx, bson.D{{Key: "_id", Value: doc.Id}}, update)
+	if err != nil {
+		return utilities.WrapMongoError(err)
+	}
+	cmd.Println(verb, strings.Join(changed, ", "))
+	return nil
+}
+
+var bansListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the ban list",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, client, _, doc, err := findFolderrDocument(context.TODO())
+		if err != nil {
+			return err
+		}
+		disconnectDB(client)
+		bans := append([]string{}, doc.Bans...)
+		sort.Strings(bans)
+		if bansListJSON {
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(bans)
+		}
+		if len(bans) == 0 {
+			cmd.Println("Nobody is banned")
+			return nil
+		}
+		for _, ban := range bans {
+			cmd.Println(ban)
+		}
+		return nil
+	},
+}
+
+var bansAddCmd = &cobra.Command{
+	Use:     "add <email|ip|cidr>...",
+	Short:   "Ban emails, IPs or IP ranges",
+	Example: "  " + utilities.Constants.RootCmdName + " bans add spammer@example.com 192.0.2.1 198.51.100.0/24",
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bans, err := normalizeBans(args)
+		if err != nil {
+			return err
+		}
+		return updateBans(cmd, bans, true)
+	},
+}
+
+var bansRemoveCmd = &cobra.Command{
+	Use:   "remove <email|ip|cidr>...",
+	Short: "Unban emails, IPs or IP ranges",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bans, err := normalizeBans(args)
+		if err != nil {
+			return err
+		}
+		return updateBans(cmd, bans, false)
+	},
+}
+
+var bansImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Ban every entry in a file",
+	Long: `Ban every entry in a file with one entry per line, or a CSV file with the entries in the first column.
+Blank lines, lines starting with # and a header row are skipped. Use "-" to read from stdin.
+Nothing is banned if any entry is invalid`,
+	Example: "  " + utilities.Constants.RootCmdName + " bans import banned.csv",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var input io.Reader = cmd.InOrStdin()
+		if args[0] != "-" {
+			file, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			input = file
+		}
+		bans, errs := utilities.ParseBanList(input)
+		if len(errs) > 0 {
+			lines := []string{}
+			for _, err := range errs {
+				lines = append(lines, err.Error())
+			}
+			return fmt.Errorf("%v has invalid entries, nothing was banned:\n%v", args[0], strings.Join(lines, "\n"))
+		}
+		if len(bans) == 0 {
+			return fmt.Errorf("%v has no entries", args[0])
+		}
+		return updateBans(cmd, bans, true)
+	},
+}
+
+var bansExportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Write the ban list to a file, one entry per line",
+	Long: `Write the ban list to a file (or stdout), one entry per line.
+The file can be imported with "` + utilities.Constants.RootCmdName + ` bans import"`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, client, _, doc, err := findFolderrDocument(context.TODO())
+		if err != nil {
+			return err
+		}
+		disconnectDB(client)
+		bans := append([]string{}, doc.Bans...)
+		sort.Strings(bans)
+		contents := strings.Join(bans, "\n")
+		if len(bans) > 0 {
+			contents += "\n"
+		}
+		if len(args) == 0 {
+			_, err = io.WriteString(cmd.OutOrStdout(), contents)
+			return err
+		}
+		if dry {
+			cmd.Println("Exported", len(bans), "bans to", args[0], "\nNOTICE: Did NOT save, due to dry run")
+			return nil
+		}
+		err = os.WriteFile(args[0], []byte(contents), 0600)
+		if err != nil {
+			return err
+		}
+		cmd.Println("Exported", len(bans), "bans to", args[0])
+		return nil
+	},
+}
+
+func init() {
+	bansListCmd.Flags().BoolVar(&bansListJSON, "json", false, "Output JSON")
+	bansCmd.AddCommand(bansListCmd, bansAddCmd, bansRemoveCmd, bansImportCmd, bansExportCmd)
+	RootCmd.AddCommand(bansCmd)
+}
@@ -0,0 +1,92 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Folderr/foldcli/cmd"
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "View and change the Folderr CLI config",
+	Long: `View and change the Folderr CLI config without re-running the init commands
+Known keys: ` + strings.Join(utilities.ConfigKeyNames(), ", "),
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(configCmd)
+}
+
+// Reads the config the same way every other command does.
+//
+// An invalid config is only a warning here, as these commands are how it gets fixed
+func readConfig(command *cobra.Command) (*viper.Viper, utilities.SecretConfig, bool, error) {
+	dry, err := command.Flags().GetBool("dry")
+	if err != nil {
+		return nil, utilities.SecretConfig{}, false, fmt.Errorf("unexpected error: %w", err)
+	}
+	dir, err := utilities.GetConfigDir(dry)
+	if err != nil {
+		return nil, utilities.SecretConfig{}, dry, err
+	}
+	vip, _, secrets, err := utilities.ReadConfig(dir, dry)
+	var configErr *utilities.ConfigError
+	if errors.As(err, &configErr) {
+		command.PrintErrln("Warning:", configErr)
+		return vip, secrets, dry, nil
+	}
+	return vip, secrets, dry, err
+}
+
+// Reads the config file on its own, for commands that change it
+func readConfigFile(command *cobra.Command) (*viper.Viper, error) {
+	dry, err := command.Flags().GetBool("dry")
+	if err != nil {
+		return nil, fmt.Errorf("unexpected error: %w", err)
+	}
+	dir, err := utilities.GetConfigDir(dry)
+	if err != nil {
+		return nil, err
+	}
+	return utilities.ReadConfigFile(dir)
+}
+
+// Returns the value of key and where it comes from, the same way every other command resolves it
+func resolveKey(vip *viper.Viper, secrets utilities.SecretConfig, key utilities.ConfigKey) (interface{}, string) {
+	value := vip.Get(key.Name)
+	if value == nil {
+		value = key.Default
+	}
+	source := key.Source(vip)
+	// The secrets file wins over the config file, but not the environment
+	if key.Name == utilities.SecretDBUrl && secrets.DBUrl != "" && source != utilities.SourceEnv {
+		return secrets.DBUrl, utilities.SourceSecrets
+	}
+	return value, source
+}
+
+func findKey(name string) (utilities.ConfigKey, error) {
+	key, ok := utilities.FindConfigKey(name)
+	if !ok {
+		return key, fmt.Errorf("unknown config key %q. Known keys: %v", name, strings.Join(utilities.ConfigKeyNames(), ", "))
+	}
+	return key, nil
+}
+
+func completeKeys(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return utilities.ConfigKeyNames(), cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,114 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func findEditor() string {
+	if editor := os.Getenv("VISUAL"); editor != "" {
+		return editor
+	}
+	if editor := os.Getenv("EDITOR"); editor != "" {
+		return editor
+	}
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+var editCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open the config file in your editor",
+	Long: `Open the config file in your editor ($VISUAL, then $EDITOR)
+A copy of the config is edited. It is only saved if every key is still valid`,
+	Args: cobra.NoArgs,
+	RunE: func(command *cobra.Command, args []string) error {
+		vip, _, dry, err := readConfig(command)
+		if err != nil {
+			return err
+		}
+		path := vip.ConfigFileUsed()
+		original, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		temp, err := os.CreateTemp("", "foldcli-config-*.yaml")
+		if err != nil {
+			return err
+		}
+		tempPath := temp.Name()
+		_, err = temp.Write(original)
+		temp.Close()
+		if err != nil {
+			os.Remove(tempPath)
+			return err
+		}
+
+		// Editors like "code --wait" come with arguments
+		editor := strings.Fields(findEditor())
+		editorCmd := exec.Command(editor[0], append(editor[1:], tempPath)...)
+		editorCmd.Stdin = os.Stdin
+		editorCmd.Stdout = os.Stdout
+		editorCmd.Stderr = os.Stderr
+		err = editorCmd.Run()
+		if err != nil {
+			os.Remove(tempPath)
+			return fmt.Errorf("editor %q failed: %w", editor[0], err)
+		}
+
+		edited, err := os.ReadFile(tempPath)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(edited, original) {
+			os.Remove(tempPath)
+			command.Println("No changes made")
+			return nil
+		}
+
+		check := viper.New()
+		check.SetConfigType("yaml")
+		err = check.ReadConfig(bytes.NewReader(edited))
+		if err != nil {
+			return fmt.Errorf("the edited config is not valid YAML, your changes are kept in %q\nError: %w", tempPath, err)
+		}
+		if errs := utilities.ValidateConfig(check); len(errs) > 0 {
+			command.Println("The edited config is invalid:")
+			for _, err := range errs {
+				command.Println("  -", err)
+			}
+			return errors.New("config not saved, your changes are kept in \"" + tempPath + "\"")
+		}
+
+		os.Remove(tempPath)
+		if dry {
+			command.Println("Config is valid\nNOTICE: Did NOT save, due to dry run")
+			return nil
+		}
+		err = os.WriteFile(path, edited, 0600)
+		if err != nil {
+			return err
+		}
+		command.Println("Saved config")
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(editCmd)
+}
@@ -0,0 +1,43 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var showSecrets bool
+
+var getCmd = &cobra.Command{
+	Use:               "get <key>",
+	Short:             "Print the value of a config key",
+	Example:           "  foldcli config get db.dbName\n  foldcli config get db.url --show-secrets",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeKeys,
+	RunE: func(command *cobra.Command, args []string) error {
+		key, err := findKey(args[0])
+		if err != nil {
+			return err
+		}
+		vip, secrets, _, err := readConfig(command)
+		if err != nil {
+			return err
+		}
+
+		value, _ := resolveKey(vip, secrets, key)
+		if showSecrets && value != nil {
+			command.Println(fmt.Sprint(value))
+			return nil
+		}
+		command.Println(key.Display(value))
+		return nil
+	},
+}
+
+func init() {
+	getCmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "Print secret values instead of redacting them")
+	configCmd.AddCommand(getCmd)
+}
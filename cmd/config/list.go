@@ -0,0 +1,43 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package config
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List every config key, its value and where the value comes from",
+	Long:    "List every config key, its value and where the value comes from (file, env, secrets or default)\nSecrets are redacted unless --show-secrets is passed",
+	Aliases: []string{"ls"},
+	Args:    cobra.NoArgs,
+	RunE: func(command *cobra.Command, args []string) error {
+		vip, secrets, _, err := readConfig(command)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(command.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+		for _, key := range utilities.ConfigKeys {
+			value, source := resolveKey(vip, secrets, key)
+			display := key.Display(value)
+			if showSecrets && value != nil {
+				display = fmt.Sprint(value)
+			}
+			fmt.Fprintf(w, "%v\t%v\t%v\n", key.Name, display, source)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	listCmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "Print secret values instead of redacting them")
+	configCmd.AddCommand(listCmd)
+}
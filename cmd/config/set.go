@@ -0,0 +1,113 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package config
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/spf13/cobra"
+)
+
+var setCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change the value of a config key",
+	Long: `Change the value of a config key
+The value is checked against the type of the key before it is saved.
+A database URI with credentials in it is refused, save it with "` + utilities.Constants.RootCmdName + ` secrets set db.url" instead`,
+	Example:           "  foldcli config set directory /home/folderr/folderr\n  foldcli config set releaseType tag",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeKeys,
+	RunE: func(command *cobra.Command, args []string) error {
+		key, err := findKey(args[0])
+		if err != nil {
+			return err
+		}
+		if key.ReadOnly {
+			return fmt.Errorf("%v is managed by %v and can't be changed", key.Name, utilities.Constants.RootCmdName)
+		}
+		value, err := key.Parse(args[1])
+		if err != nil {
+			return err
+		}
+		if key.Name == utilities.SecretDBUrl {
+			// Credentials belong in the secrets file, not in plain text
+			if uri, err := url.Parse(args[1]); err == nil && uri.User != nil {
+				return fmt.Errorf("the database URI has credentials in it. Save it with \"%v secrets set %v\" instead", utilities.Constants.RootCmdName, key.Name)
+			}
+		}
+		vip, secrets, dry, err := readConfig(command)
+		if err != nil {
+			return err
+		}
+		switch _, source := resolveKey(vip, secrets, key); source {
+		case utilities.SourceEnv:
+			command.Printf("Notice: %v is set by the %v environment variable, which takes priority over the config file\n", key.Name, key.Env)
+		case utilities.SourceSecrets:
+			command.Printf("Notice: %v is set in the secrets file, which takes priority over the config file\n", key.Name)
+		}
+
+		if dry {
+			command.Printf("Set %v to %v\nNOTICE: Did NOT save, due to dry run\n", key.Name, key.Display(value))
+			return nil
+		}
+		// vip has the env overrides in it, those mustn't end up in the file
+		file, err := readConfigFile(command)
+		if err != nil {
+			return err
+		}
+		file.Set(key.Name, value)
+		err = file.WriteConfig()
+		if err != nil {
+			return err
+		}
+		command.Printf("Set %v to %v\n", key.Name, key.Display(value))
+		return nil
+	},
+}
+
+var unsetCmd = &cobra.Command{
+	Use:               "unset <key>",
+	Short:             "Remove a key from the config",
+	Example:           "  foldcli config unset release",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeKeys,
+	RunE: func(command *cobra.Command, args []string) error {
+		key, err := findKey(args[0])
+		if err != nil {
+			return err
+		}
+		if key.ReadOnly {
+			return fmt.Errorf("%v is managed by %v and can't be removed", key.Name, utilities.Constants.RootCmdName)
+		}
+		vip, _, dry, err := readConfig(command)
+		if err != nil {
+			return err
+		}
+		if !vip.InConfig(key.Name) {
+			command.Println(key.Name, "is not set in the config file")
+			return nil
+		}
+		if dry {
+			command.Printf("Removed %v\nNOTICE: Did NOT save, due to dry run\n", key.Name)
+			return nil
+		}
+		file, err := readConfigFile(command)
+		if err != nil {
+			return err
+		}
+		err = utilities.UnsetConfigKey(file, key.Name)
+		if err != nil {
+			return err
+		}
+		command.Println("Removed", key.Name)
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(setCmd)
+	configCmd.AddCommand(unsetCmd)
+}
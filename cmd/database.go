@@ -0,0 +1,127 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// How long database commands wait for the server by default
+const defaultDBTimeout = 10 * time.Second
+
+// dbCmd represents the db command
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage Folderr's database",
+	Long: `Manage Folderr's database
+Uses the database from "` + utilities.Constants.RootCmdName + ` init db"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(dbCmd)
+}
+
+// Reads the config for commands that use Folderr's database
+func readDBConfig() (utilities.Config, error) {
+	dir, err := utilities.GetConfigDir(dry)
+	if err != nil {
+		return utilities.Config{}, err
+	}
+	_, config, secrets, err := utilities.ReadConfig(dir, dry)
+	if err != nil {
+		return config, err
+	}
+	if err = utilities.RequireDBSecrets(config, secrets); err != nil {
+		return config, err
+	}
+	uri, err := utilities.LookupSecretEnv(utilities.Constants.EnvPrefix + "MONGO_URI")
+	if err != nil {
+		return config, err
+	}
+	if uri != "" {
+		config.Database.Url = uri
+	}
+	if config.Database.Url == "" || config.Database.DbName == "" {
+		return config, errors.New("please run \"" + rootCmdName + " init db\" before running this command. thanks")
+	}
+	return config, nil
+}
+
+func disconnectDB(client *mongo.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client.Disconnect(ctx)
+}
+
+// Transactions need a replica set or a sharded cluster
+func supportsTransactions(ctx context.Context, client *mongo.Client) (bool, error) {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		return false, err
+	}
+	return hello.SetName != "" || hello.Msg == "isdbgrid", nil
+}
+
+// The document "setup db" makes in the folderrs collection
+type folderrDocument struct {
+	Id                primitive.ObjectID `bson:"_id"`
+	Bans              []string           `bson:"bans"`
+	PublicKeyJWT      bson.RawValue      `bson:"publicKeyJWT"`
+	PreviousPublicKey bson.RawValue      `bson:"previousPublicKeyJWT,omitempty"`
+	PreviousExpiresAt time.Time          `bson:"previousPublicKeyExpiresAt,omitempty"`
+}
+
+// Returns the PEM of a public key saved in the database, "setup db" saves binary but Folderr may save strings
+func publicKeyPem(value bson.RawValue) ([]byte, error) {
+	switch value.Type {
+	case bsontype.Binary:
+		_, data := value.Binary()
+		return data, nil
+	case bsontype.String:
+		return []byte(value.StringValue()), nil
+	case 0, bsontype.Null:
+		return nil, errors.New("no public key saved")
+	}
+	return nil, fmt.Errorf("public key is saved as %v, expected binary or a string", value.Type)
+}
+
+// Reads the config & finds the folderrs document, for commands that manage the instance
+func findFolderrDocument(ctx context.Context) (utilities.Config, *mongo.Client, *mongo.Collection, folderrDocument, error) {
+	doc := folderrDocument{}
+	config, err := readDBConfig()
+	if err != nil {
+		return config, nil, nil, doc, err
+	}
+	client, err := utilities.NewMongoClient(config.Database, defaultDBTimeout)
+	if err != nil {
+		return config, nil, nil, doc, utilities.WrapMongoError(err)
+	}
+	coll := client.Database(config.Database.DbName).Collection("folderrs")
+	err = coll.FindOne(ctx, bson.D{}).Decode(&doc)
+	if err != nil {
+		disconnectDB(client)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return config, nil, nil, doc, errors.New("Folderr's database isn't set up. Run \"" + rootCmdName + " setup db\" first")
+		}
+		return config, nil, nil, doc, utilities.WrapMongoError(err)
+	}
+	return config, client, coll, doc, nil
+}
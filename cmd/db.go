@@ -9,7 +9,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -18,7 +17,6 @@ import (
 	"github.com/spf13/cobra"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var verbose, noCleanup bool
@@ -43,16 +41,23 @@ REQUIRES Folderr to be installed`,
 		if err != nil {
 			panic(err)
 		}
-		_, config, _, err := utilities.ReadConfig(dir, dry)
+		_, config, secrets, err := utilities.ReadConfig(dir, dry)
 		if err != nil {
 			cmd.Println("Failed to read config. see below")
 			return err
 		}
+		if err = utilities.RequireDBSecrets(config, secrets); err != nil {
+			return err
+		}
 		if dry {
 			return fmt.Errorf(`command \"` + rootCmdName + ` setup db\" does not have dry-run mode
 Run with test env var for automatic cleanup of files and database entries`)
 		}
 
+		if err = keyOptions().CheckFolderrCompatible(); err != nil {
+			return err
+		}
+
 		checkInit := utilities.CheckInitialization(&config)
 		if !checkInit.Folderr {
 			cmd.Println("Run \"" + utilities.Constants.RootCmdName + " init folderr\" before running this command. thanks")
@@ -67,7 +72,10 @@ Run with test env var for automatic cleanup of files and database entries`)
 			cmd.Println("Please install Folderr. Here's a command to do so.")
 			return nil
 		}
-		uri := os.Getenv(utilities.Constants.EnvPrefix + "MONGO_URI")
+		uri, err := utilities.LookupSecretEnv(utilities.Constants.EnvPrefix + "MONGO_URI")
+		if err != nil {
+			return err
+		}
 		if (config.Database.Url == "" && uri != "") || config.Database.DbName == "" {
 			cmd.Println("run \"" + utilities.Constants.RootCmdName + " init db\" before this command. thanks")
 			initDbCmd, _, err := cmd.Root().Find([]string{"init db"})
@@ -88,24 +96,12 @@ Run with test env var for automatic cleanup of files and database entries`)
 			}
 		}
 
-		if _, err = os.Stat(save_dir); err != nil {
-			if errors.Is(err, fs.ErrNotExist) {
-				os.MkdirAll(save_dir, 0700)
-			} else {
-				return err
-			}
-		}
-
-		client, err := mongo.Connect(context.TODO(), options.Client().ApplyURI(uri))
+		config.Database.Url = uri
+		client, err := utilities.NewMongoClient(config.Database, defaultDBTimeout)
 		if err != nil {
-			panic(err)
+			return utilities.WrapMongoError(err)
 		}
-
-		defer func() {
-			if client.Disconnect(context.TODO()) != nil {
-				panic(err)
-			}
-		}()
+		defer disconnectDB(client)
 
 		if os.Getenv("test") == "true" && !noCleanup {
 			defer cleanupFolderrDbCmd(cmd.OutOrStdout(), config, args[0], save_dir)
@@ -115,74 +111,93 @@ Run with test env var for automatic cleanup of files and database entries`)
 		coll := db.Collection("folderrs")
 		fldrr := coll.FindOne(context.TODO(), bson.D{})
 		err = fldrr.Err()
-		if err != mongo.ErrNoDocuments {
-			if mongo.IsTimeout(err) {
-				cmd.Println("Server Timeout Error:", err.Error(), "\nThis can mean that the server is offline, you're offline, or there is (at least) a firewall in the way")
+		if kind := utilities.ClassifyMongoError(err); kind != utilities.MongoNotFound {
+			if kind != utilities.MongoOK {
+				cmd.Println(utilities.DescribeMongoError(err))
 				return nil
-			} else if mongo.IsNetworkError(err) {
-				cmd.Println("Network Error:", err.Error())
-				return nil
-			} else if err != nil {
-				if strings.Contains(err.Error(), "Unauthorized") || strings.Contains(err.Error(), "unauthorized") {
-					println(
-						"Authorization error. Please provide authentication information in the string, before the host.\n",
-						"Alternatively the error could mean you do not have permissions on this database.\n",
-						"Error:",
-						err.Error(),
-					)
-					return nil
-				}
-				cmd.Println("Encountered error while uploading your user data")
-				cmd.Println("Please submit issue with template \"bug report\" at https://github.com/Folderr/folderr-cli/issues with the error below")
-				cmd.Println(err)
 			}
 			cmd.Println("Folderr appears to be setup")
 			return nil
 		}
 
-		privatePem, publicPem, err := utilities.GenKeys()
+		privatePem, publicPem, err := utilities.GenKeysWithOptions(keyOptions())
 		if err != nil {
 			return err
 		}
 
+		// Every step is journaled so a failure leaves things as they were
+		journal := &utilities.Journal{}
+		fail := func(err error) error {
+			cmd.Println("Setup failed, see the error below")
+			cmd.Println(utilities.DescribeRollback(journal.Rollback()))
+			return err
+		}
+
+		err = journal.MkdirAll(save_dir, 0700)
+		if err != nil {
+			return fail(err)
+		}
 		if verbose {
-			cmd.Println("Saving private key to", save_dir+"/privateJWT.pem")
+			cmd.Println("Saving private key to", filepath.Join(save_dir, "privateJWT.pem"))
 		}
 		// write private key
-		err = os.WriteFile(save_dir+"/privateJWT.pem", privatePem, 0700)
+		err = journal.WriteFile(filepath.Join(save_dir, "privateJWT.pem"), privatePem, 0600)
 		if err != nil {
-			return err
+			return fail(err)
 		}
 		if verbose {
-			cmd.Println("Saved private key to", save_dir+"/privateJWT.pem")
+			cmd.Println("Saved private key to", filepath.Join(save_dir, "privateJWT.pem"))
 		}
 
 		if verbose {
-			cmd.Println("Saving public key to", save_dir+"/publicJWT.pem", "in case anything goes wrong")
+			cmd.Println("Saving public key to", filepath.Join(save_dir, "publicJWT.pem"), "in case anything goes wrong")
 		}
 		// write public key in case something goes wrong
-		err = os.WriteFile(save_dir+"/publicJWT.pem", publicPem, 0755)
+		err = journal.WriteFile(filepath.Join(save_dir, "publicJWT.pem"), publicPem, 0644)
 		if err != nil {
-			return err
+			return fail(err)
 		}
 		if verbose {
-			cmd.Print("Saved public key to", save_dir+"/publicJWT.pem", "in case anything goes wrong\n\n")
+			cmd.Print("Saved public key to", filepath.Join(save_dir, "publicJWT.pem"), "in case anything goes wrong\n\n")
 		}
 		cmd.Println("The keys were saved in", save_dir, "under 'privateJWT.pem' and 'publicJWT.pem'")
-		err = saveKeyToFolderr(save_dir, config, privatePem)
+		err = saveKeyToFolderr(journal, save_dir, config, privatePem)
 		if err != nil {
-			cmd.Println(err.Error())
-		} else {
-			cmd.Println("Installed key to Folderr")
+			return fail(err)
 		}
+		cmd.Println("Installed key to Folderr")
+
 		FolderrDbInsertedId, err = coll.InsertOne(context.TODO(), bson.D{
 			{Key: "bans", Value: []string{}},
 			{Key: "publicKeyJWT", Value: publicPem},
 		})
 		if err != nil {
-			panic(err)
-		} else {
-			cmd.Println("Saved public key to database")
+			return fail(fmt.Errorf("failed to save the public key to the database\n%w", utilities.WrapMongoError(err)))
+		}
+		insertedId := FolderrDbInsertedId.InsertedID
+		journal.Done("deleted the public key from the database", func() error {
+			_, err := coll.DeleteOne(context.TODO(), bson.D{{Key: "_id", Value: insertedId}})
+			if err == nil {
+				FolderrDbInsertedId = nil
+			}
+			return err
+		})
+		cmd.Println("Saved public key to database")
+
+		// Fresh database, so it starts at the latest schema version
+		err = migrateNewDatabase(context.TODO(), db, journal)
+		if errors.Is(err, utilities.ErrMigrationLocked) {
+			return fail(err)
+		} else if err != nil {
+			return fail(fmt.Errorf("failed to migrate the database\n%w", utilities.WrapMongoError(err)))
+		}
+
+		// Fresh database, so Folderr's indexes are created before anyone signs up
+		created, _, err := utilities.EnsureIndexes(context.TODO(), db, utilities.FolderrIndexes)
+		if err != nil {
+			cmd.Println("Failed to create indexes, run \"" + rootCmdName + " db indexes ensure\" to try again\n" + utilities.DescribeMongoError(err))
+		} else if verbose {
+			cmd.Println("Created", len(created), "indexes")
 		}
 
 		// formattedKey := string(privatePem)
@@ -191,12 +206,47 @@ Run with test env var for automatic cleanup of files and database entries`)
 	},
 }
 
+// Brings a newly setup database to the latest schema version, under the same lock as "db migrate".
+// Applied migrations are recorded in journal
+func migrateNewDatabase(ctx context.Context, db *mongo.Database, journal *utilities.Journal) error {
+	release, err := utilities.AcquireMigrationLock(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	current, err := utilities.SchemaVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+	steps, err := utilities.PlanMigrations(utilities.Migrations, current, utilities.LatestMigrationVersion())
+	if err != nil {
+		return err
+	}
+	for _, step := range steps {
+		if err = utilities.RunMigrationStep(ctx, db, step); err != nil {
+			return err
+		}
+		migration := step.Migration
+		journal.Done(fmt.Sprintf("undid migration %v", migration.Version), func() error {
+			if migration.Down != nil {
+				return utilities.RunMigrationStep(ctx, db, utilities.MigrationStep{Migration: migration, Down: true})
+			}
+			// Nothing to undo on a new database, only the record
+			_, err := db.Collection(utilities.MigrationsCollection).DeleteOne(ctx, bson.D{{Key: "version", Value: migration.Version}})
+			return err
+		})
+	}
+	return nil
+}
+
 type locationJSON struct {
 	Keys          string `json:"keys"`
 	KeyConfigured bool   `json:"keyConfigured"`
 }
 
-func saveKeyToFolderr(save_dir string, config utilities.Config, privateKey []byte) error {
+// Installs the private key into Folderr. The writes are recorded in journal, which can be nil
+func saveKeyToFolderr(journal *utilities.Journal, save_dir string, config utilities.Config, privateKey []byte) error {
 	dir := filepath.Join(config.Directory, "internal/keys")
 	privatePath := filepath.Join(dir, "privateJWT.pem")
 	_, err := os.Stat(filepath.Join(config.Directory, "internal/keys"))
@@ -214,7 +264,7 @@ func saveKeyToFolderr(save_dir string, config utilities.Config, privateKey []byt
 			err,
 		)
 	}
-	err = os.WriteFile(privatePath, privateKey, 0600)
+	err = journal.WriteFile(privatePath, privateKey, 0600)
 	if err != nil {
 		return fmt.Errorf(
 			`failed to write the private key to Folderr, you need to copy it from "%v" to "%v".
@@ -241,7 +291,7 @@ func saveKeyToFolderr(save_dir string, config utilities.Config, privateKey []byt
 		)
 	}
 
-	err = os.WriteFile(locationsPath, marshal, 0600)
+	err = journal.WriteFile(locationsPath, marshal, 0600)
 	if err != nil {
 		return fmt.Errorf(
 			"failed to write \"%v\". You must do it yourself\nIt should look like %v\nOriginal error: %w",
@@ -255,20 +305,14 @@ func saveKeyToFolderr(save_dir string, config utilities.Config, privateKey []byt
 
 func cleanupFolderrDbCmd(w io.Writer, config utilities.Config, dbName, path string) {
 	// make a new database connection
-	uri := config.Database.Url
 	fmt.Fprintln(w, path)
 
-	client, err := mongo.Connect(context.TODO(), options.Client().ApplyURI(uri).SetAppName("Folderr CLI"))
+	client, err := utilities.NewMongoClient(config.Database, defaultDBTimeout)
 	if err != nil {
 		println("DB Connection failed. See panic.")
 		panic(err)
 	}
-
-	defer func() {
-		if client.Disconnect(context.TODO()) != nil {
-			panic(err)
-		}
-	}()
+	defer disconnectDB(client)
 
 	cleaned := []string{}
 
@@ -346,6 +390,7 @@ func cleanupFolderrDbCmd(w io.Writer, config utilities.Config, dbName, path stri
 func init() {
 	folderrDBCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Shows information aside from key output.")
 	folderrDBCmd.Flags().BoolVar(&noCleanup, "no-cleanup", false, "Does not cleanup if running in test mode. Only useful for data peekers and developers.")
+	addKeyFlags(folderrDBCmd)
 	setupCmd.AddCommand(folderrDBCmd)
 
 	// Here you will define your flags and configuration settings.
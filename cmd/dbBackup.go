@@ -0,0 +1,180 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package cmd
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var restoreDrop bool
+var restoreDbName string
+
+var dbBackupCmd = &cobra.Command{
+	Use:   "backup <file>",
+	Short: "Back up Folderr's database to a file",
+	Long: `Back up every collection of Folderr's database to a zip archive.
+Each collection is saved as Extended JSON, one document per line, next to a manifest
+with the document counts, the CLI version and the schema version.
+Restore it with "` + utilities.Constants.RootCmdName + ` db restore"`,
+	Example: "  " + utilities.Constants.RootCmdName + " db backup folderr-backup.zip",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := args[0]
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%v already exists. Remove it or choose another file", path)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		config, err := readDBConfig()
+		if err != nil {
+			return err
+		}
+		client, err := utilities.NewMongoClient(config.Database, defaultDBTimeout)
+		if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		defer disconnectDB(client)
+		db := client.Database(config.Database.DbName)
+
+		if dry {
+			names, err := db.ListCollectionNames(context.TODO(), bson.D{})
+			if err != nil {
+				return utilities.WrapMongoError(err)
+			}
+			cmd.Println("Would back up", strings.Join(names, ", "), "to", path)
+			cmd.Println("NOTICE: Did NOT save, due to dry run")
+			return nil
+		}
+
+		// Written next to the backup then renamed, so a failed backup never looks complete
+		tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		manifest, err := utilities.BackupDatabase(context.TODO(), db, tmp, RootCmd.Version)
+		closeErr := tmp.Close()
+		if err != nil {
+			return fmt.Errorf("backup failed\n%w", utilities.WrapMongoError(err))
+		}
+		if closeErr != nil {
+			return closeErr
+		}
+		if err = os.Chmod(tmp.Name(), 0600); err != nil {
+			return err
+		}
+		if err = os.Rename(tmp.Name(), path); err != nil {
+			return err
+		}
+
+		for _, name := range manifest.CollectionNames() {
+			cmd.Printf("Backed up %v documents from %v\n", manifest.Collections[name], name)
+		}
+		cmd.Println("Saved the backup of", config.Database.DbName, "to", path)
+		return nil
+	},
+}
+
+var dbRestoreCmd = &cobra.Command{
+	Use:   "restore <file>",
+	Short: "Restore Folderr's database from a backup",
+	Long: `Restore a backup made by "` + utilities.Constants.RootCmdName + ` db backup".
+Restores into Folderr's database unless --db is given. Collections in the backup must be empty
+unless --drop is given. With --drop each collection is restored next to the original, then replaces it,
+so a failed restore leaves that collection as it was.
+The document counts are checked against the backup's manifest afterwards, and Folderr's indexes are created`,
+	Example: "  " + utilities.Constants.RootCmdName + " db restore folderr-backup.zip --drop\n  " +
+		utilities.Constants.RootCmdName + " db restore folderr-backup.zip --db folderr-staging",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		archive, err := zip.OpenReader(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to open backup %v: %w", args[0], err)
+		}
+		defer archive.Close()
+		manifest, err := utilities.ReadBackupManifest(&archive.Reader)
+		if err != nil {
+			return err
+		}
+		if manifest.SchemaVersion > utilities.LatestMigrationVersion() {
+			return fmt.Errorf(
+				"the backup has schema version %v, newer than this CLI knows (%v). Update the CLI",
+				manifest.SchemaVersion,
+				utilities.LatestMigrationVersion(),
+			)
+		}
+
+		config, err := readDBConfig()
+		if err != nil {
+			return err
+		}
+		dbName := config.Database.DbName
+		if restoreDbName != "" {
+			dbName = restoreDbName
+		}
+		cmd.Printf(
+			"Backup of %v made %v by %v %v, schema version %v\n",
+			manifest.Database,
+			manifest.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+			rootCmdName,
+			manifest.FoldcliVersion,
+			manifest.SchemaVersion,
+		)
+		if dry {
+			for _, name := range manifest.CollectionNames() {
+				cmd.Printf("Would restore %v documents to %v.%v\n", manifest.Collections[name], dbName, name)
+			}
+			cmd.Println("NOTICE: Did NOT restore, due to dry run")
+			return nil
+		}
+
+		client, err := utilities.NewMongoClient(config.Database, defaultDBTimeout)
+		if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		defer disconnectDB(client)
+		db := client.Database(dbName)
+
+		err = utilities.RestoreDatabase(context.TODO(), db, &archive.Reader, manifest, restoreDrop)
+		if err != nil {
+			if utilities.ClassifyMongoError(err) == utilities.MongoUnknown {
+				return err
+			}
+			return fmt.Errorf("restore failed\n%w", utilities.WrapMongoError(err))
+		}
+		mismatched, err := utilities.VerifyRestore(context.TODO(), db, manifest)
+		if err != nil {
+			return fmt.Errorf("failed to check the restore\n%w", utilities.WrapMongoError(err))
+		}
+		if len(mismatched) > 0 {
+			return fmt.Errorf("the restored database doesn't match the backup:\n  %v", strings.Join(mismatched, "\n  "))
+		}
+		_, _, err = utilities.EnsureIndexes(context.TODO(), db, utilities.FolderrIndexes)
+		if err != nil {
+			cmd.Println("Failed to create indexes, run \"" + rootCmdName + " db indexes ensure\" to try again\n" + utilities.DescribeMongoError(err))
+		}
+		for _, name := range manifest.CollectionNames() {
+			cmd.Printf("Restored %v documents to %v\n", manifest.Collections[name], name)
+		}
+		cmd.Println("Restored", args[0], "to", dbName)
+		return nil
+	},
+}
+
+func init() {
+	dbRestoreCmd.Flags().BoolVar(&restoreDrop, "drop", false, "Replace the collections in the backup, instead of requiring them to be empty")
+	dbRestoreCmd.Flags().StringVar(&restoreDbName, "db", "", "Database to restore into. Default: Folderr's database")
+	dbCmd.AddCommand(dbBackupCmd, dbRestoreCmd)
+}
@@ -0,0 +1,168 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// Scratch collection the write check uses. Dropped after the check
+const dbCheckCollection = "foldcli_checks"
+
+var dbCheckTimeout time.Duration
+
+type dbCheckResult struct {
+	name   string
+	result string
+	err    error
+}
+
+// Runs the checks in order. Stops at the first failure of a check that the rest depend on
+func runDBChecks(ctx context.Context, client *mongo.Client, dbName string) []dbCheckResult {
+	results := []dbCheckResult{}
+	admin := client.Database("admin")
+	db := client.Database(dbName)
+
+	start := time.Now()
+	err := client.Ping(ctx, readpref.Primary())
+	results = append(results, dbCheckResult{"ping", fmt.Sprintf("ok (%v)", time.Since(start).Round(time.Millisecond)), err})
+	if err != nil {
+		return results
+	}
+
+	var buildInfo struct {
+		Version string `bson:"version"`
+	}
+	err = admin.RunCommand(ctx, bson.D{{Key: "buildInfo", Value: 1}}).Decode(&buildInfo)
+	results = append(results, dbCheckResult{"server version", buildInfo.Version, err})
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	err = admin.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	topology := "standalone"
+	if hello.SetName != "" {
+		topology = "replica set \"" + hello.SetName + "\""
+	} else if hello.Msg == "isdbgrid" {
+		topology = "sharded cluster (mongos)"
+	}
+	results = append(results, dbCheckResult{"topology", topology, err})
+
+	var status struct {
+		AuthInfo struct {
+			Users []struct {
+				User string `bson:"user"`
+				DB   string `bson:"db"`
+			} `bson:"authenticatedUsers"`
+			Roles []struct {
+				Role string `bson:"role"`
+				DB   string `bson:"db"`
+			} `bson:"authenticatedUserRoles"`
+		} `bson:"authInfo"`
+	}
+	err = admin.RunCommand(ctx, bson.D{{Key: "connectionStatus", Value: 1}}).Decode(&status)
+	auth := "not authenticated"
+	if len(status.AuthInfo.Users) > 0 {
+		users := []string{}
+		for _, user := range status.AuthInfo.Users {
+			users = append(users, user.User+"@"+user.DB)
+		}
+		roles := []string{}
+		for _, role := range status.AuthInfo.Roles {
+			roles = append(roles, role.Role+"@"+role.DB)
+		}
+		auth = "as " + strings.Join(users, ", ") + " (roles: " + strings.Join(roles, ", ") + ")"
+	}
+	results = append(results, dbCheckResult{"authentication", auth, err})
+
+	_, err = db.ListCollectionNames(ctx, bson.D{})
+	if err == nil {
+		err = db.Collection("users").FindOne(ctx, bson.D{}).Err()
+		if err == mongo.ErrNoDocuments {
+			err = nil
+		}
+	}
+	results = append(results, dbCheckResult{"read " + dbName, "ok", err})
+
+	if dry {
+		results = append(results, dbCheckResult{"write " + dbName, "skipped (dry run)", nil})
+		return results
+	}
+	coll := db.Collection(dbCheckCollection)
+	_, err = coll.InsertOne(ctx, bson.D{{Key: "checkedAt", Value: time.Now()}})
+	if err == nil {
+		err = coll.Drop(ctx)
+	}
+	results = append(results, dbCheckResult{"write " + dbName, "ok", err})
+	return results
+}
+
+var dbCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check that the database is reachable and usable",
+	Long: `Check that the database is reachable and usable by Folderr.
+Pings the server, shows its version, topology & who you're authenticated as,
+then checks you can read and write Folderr's database.
+The write check adds and drops a "` + dbCheckCollection + `" collection, it's skipped in dry run mode`,
+	Example: "  " + utilities.Constants.RootCmdName + " db check\n  " + utilities.Constants.RootCmdName + " db check --timeout 30s",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := readDBConfig()
+		if err != nil {
+			return err
+		}
+		// An explicit --timeout wins over the timeouts from "init db"
+		if cmd.Flags().Changed("timeout") {
+			config.Database.ConnectTimeout = ""
+			config.Database.ServerSelectionTimeout = ""
+		}
+		client, err := utilities.NewMongoClient(config.Database, dbCheckTimeout)
+		if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		defer disconnectDB(client)
+
+		ctx, cancel := context.WithTimeout(context.Background(), dbCheckTimeout*3)
+		defer cancel()
+		results := runDBChecks(ctx, client, config.Database.DbName)
+
+		failed := []dbCheckResult{}
+		writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		for _, result := range results {
+			if result.err != nil {
+				fmt.Fprintf(writer, "%v\tFAILED (%v)\n", result.name, utilities.ClassifyMongoError(result.err))
+				failed = append(failed, result)
+				continue
+			}
+			fmt.Fprintf(writer, "%v\t%v\n", result.name, result.result)
+		}
+		writer.Flush()
+
+		if len(failed) == 0 {
+			cmd.Println("\nThe database is ready for Folderr")
+			return nil
+		}
+		for _, result := range failed {
+			cmd.Println("\n" + result.name + ": " + utilities.DescribeMongoError(result.err))
+		}
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%v database check(s) failed", len(failed))
+	},
+}
+
+func init() {
+	dbCheckCmd.Flags().DurationVar(&dbCheckTimeout, "timeout", defaultDBTimeout, "How long to wait for the database server")
+	dbCmd.AddCommand(dbCheckCmd)
+}
@@ -0,0 +1,163 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var dbIndexesCmd = &cobra.Command{
+	Use:   "indexes",
+	Short: "Manage the indexes Folderr needs",
+	Long: `Manage the indexes Folderr needs, such as unique usernames, emails & IDs of users.
+  ensure: create the indexes that are missing
+  list:   list the indexes of Folderr's collections
+  diff:   show missing, changed & extra indexes`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// Connects to the database for the indexes commands
+func indexesDB() (*mongo.Client, *mongo.Database, error) {
+	config, err := readDBConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := utilities.NewMongoClient(config.Database, defaultDBTimeout)
+	if err != nil {
+		return nil, nil, utilities.WrapMongoError(err)
+	}
+	return client, client.Database(config.Database.DbName), nil
+}
+
+func printIndexDiff(w io.Writer, diff utilities.IndexDiff) {
+	writer := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, spec := range diff.Missing {
+		fmt.Fprintf(writer, "missing\t%v.%v\t%v\tunique: %v\n", spec.Collection, spec.Name(), spec.KeysString(), spec.Unique)
+	}
+	for _, drift := range diff.Changed {
+		fmt.Fprintf(
+			writer,
+			"changed\t%v.%v\t%v\tunique: %v, expected %v unique: %v\n",
+			drift.Existing.Collection,
+			drift.Existing.Name,
+			drift.Existing.Keys,
+			drift.Existing.Unique,
+			drift.Spec.KeysString(),
+			drift.Spec.Unique,
+		)
+	}
+	for _, index := range diff.Extra {
+		fmt.Fprintf(writer, "extra\t%v.%v\t%v\tunique: %v\n", index.Collection, index.Name, index.Keys, index.Unique)
+	}
+	writer.Flush()
+}
+
+var dbIndexesEnsureCmd = &cobra.Command{
+	Use:   "ensure",
+	Short: "Create the indexes that are missing",
+	Long: `Create the indexes Folderr needs that are missing.
+Indexes that have changed are reported, but not replaced. Drop them and run this again to replace them`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, db, err := indexesDB()
+		if err != nil {
+			return err
+		}
+		defer disconnectDB(client)
+
+		if dry {
+			existing, err := utilities.ListIndexes(context.TODO(), db, utilities.IndexCollections(utilities.FolderrIndexes))
+			if err != nil {
+				return utilities.WrapMongoError(err)
+			}
+			diff := utilities.DiffIndexes(utilities.FolderrIndexes, existing)
+			for _, spec := range diff.Missing {
+				cmd.Println("Would create index", spec.Name(), "on", spec.Collection)
+			}
+			cmd.Println("NOTICE: Did NOT create indexes, due to dry run")
+			return nil
+		}
+
+		created, diff, err := utilities.EnsureIndexes(context.TODO(), db, utilities.FolderrIndexes)
+		for _, spec := range created {
+			cmd.Println("Created index", spec.Name(), "on", spec.Collection)
+		}
+		if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		for _, drift := range diff.Changed {
+			cmd.Printf("Index %v on %v has changed, drop it and run this again to replace it\n", drift.Existing.Name, drift.Existing.Collection)
+		}
+		if len(created) == 0 {
+			cmd.Println("All indexes exist")
+		}
+		return nil
+	},
+}
+
+var dbIndexesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the indexes of Folderr's collections",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, db, err := indexesDB()
+		if err != nil {
+			return err
+		}
+		defer disconnectDB(client)
+
+		existing, err := utilities.ListIndexes(context.TODO(), db, utilities.IndexCollections(utilities.FolderrIndexes))
+		if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, "COLLECTION\tNAME\tKEYS\tUNIQUE")
+		for _, index := range existing {
+			fmt.Fprintf(writer, "%v\t%v\t%v\t%v\n", index.Collection, index.Name, index.Keys, index.Unique)
+		}
+		return writer.Flush()
+	},
+}
+
+var dbIndexesDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show missing, changed & extra indexes",
+	Long: `Show how the indexes of Folderr's collections differ from the indexes Folderr needs.
+Exits with an error if they differ`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, db, err := indexesDB()
+		if err != nil {
+			return err
+		}
+		defer disconnectDB(client)
+
+		existing, err := utilities.ListIndexes(context.TODO(), db, utilities.IndexCollections(utilities.FolderrIndexes))
+		if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		diff := utilities.DiffIndexes(utilities.FolderrIndexes, existing)
+		if diff.Empty() {
+			cmd.Println("The indexes match what Folderr needs")
+			return nil
+		}
+		printIndexDiff(cmd.OutOrStdout(), diff)
+		cmd.SilenceUsage = true
+		return fmt.Errorf("the indexes differ from what Folderr needs. Run \"" + rootCmdName + " db indexes ensure\" to create missing indexes")
+	},
+}
+
+func init() {
+	dbIndexesCmd.AddCommand(dbIndexesEnsureCmd, dbIndexesListCmd, dbIndexesDiffCmd)
+	dbCmd.AddCommand(dbIndexesCmd)
+}
@@ -0,0 +1,139 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/spf13/cobra"
+)
+
+var migrateTo int
+
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate Folderr's database to a schema version",
+	Long: `Migrate Folderr's database to a schema version, the latest by default.
+Migrating to an older version undoes the newer migrations, if they can be undone.
+Ran migrations are recorded in the "` + utilities.MigrationsCollection + `" collection,
+which also holds a lock so two migrations can't run at once.
+With --dry the migrations that would run are listed, but not ran`,
+	Example: "  " + utilities.Constants.RootCmdName + " db migrate\n  " +
+		utilities.Constants.RootCmdName + " db migrate --to 1\n  " +
+		utilities.Constants.RootCmdName + " db migrate status",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := readDBConfig()
+		if err != nil {
+			return err
+		}
+		client, err := utilities.NewMongoClient(config.Database, defaultDBTimeout)
+		if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		defer disconnectDB(client)
+		db := client.Database(config.Database.DbName)
+		ctx := context.TODO()
+
+		target := utilities.LatestMigrationVersion()
+		if cmd.Flags().Changed("to") {
+			target = migrateTo
+		}
+
+		if !dry {
+			release, err := utilities.AcquireMigrationLock(ctx, db)
+			if errors.Is(err, utilities.ErrMigrationLocked) {
+				return err
+			} else if err != nil {
+				return utilities.WrapMongoError(err)
+			}
+			defer func() {
+				if err := release(); err != nil {
+					cmd.PrintErrln("Failed to release the migration lock:", err)
+				}
+			}()
+		}
+
+		current, err := utilities.SchemaVersion(ctx, db)
+		if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		steps, err := utilities.PlanMigrations(utilities.Migrations, current, target)
+		if err != nil {
+			return err
+		}
+		if len(steps) == 0 {
+			cmd.Println("The database is already at version", current)
+			return nil
+		}
+
+		for _, step := range steps {
+			action := "Applying"
+			if step.Down {
+				action = "Undoing"
+			}
+			cmd.Printf("%v migration %v: %v\n", action, step.Migration.Version, step.Migration.Description)
+			if dry {
+				continue
+			}
+			err = utilities.RunMigrationStep(ctx, db, step)
+			if err != nil {
+				return fmt.Errorf("migration %v failed, the database is left at the version before it\n%w", step.Migration.Version, utilities.WrapMongoError(err))
+			}
+		}
+		if dry {
+			cmd.Println("NOTICE: Did NOT migrate, due to dry run")
+			return nil
+		}
+		cmd.Printf("Migrated the database from version %v to %v\n", current, target)
+		return nil
+	},
+}
+
+var dbMigrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show which migrations have ran",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := readDBConfig()
+		if err != nil {
+			return err
+		}
+		client, err := utilities.NewMongoClient(config.Database, defaultDBTimeout)
+		if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		defer disconnectDB(client)
+
+		applied, err := utilities.AppliedMigrations(context.TODO(), client.Database(config.Database.DbName))
+		if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		appliedAt := map[int]string{}
+		for _, migration := range applied {
+			appliedAt[migration.Version] = migration.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+
+		writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, "VERSION\tAPPLIED\tDESCRIPTION")
+		for _, migration := range utilities.Migrations {
+			status, ok := appliedAt[migration.Version]
+			if !ok {
+				status = "pending"
+			}
+			fmt.Fprintf(writer, "%v\t%v\t%v\n", migration.Version, status, migration.Description)
+		}
+		return writer.Flush()
+	},
+}
+
+func init() {
+	dbMigrateCmd.Flags().IntVar(&migrateTo, "to", 0, "Schema version to migrate to. Default: the latest")
+	dbMigrateCmd.AddCommand(dbMigrateStatusCmd)
+	dbCmd.AddCommand(dbMigrateCmd)
+}
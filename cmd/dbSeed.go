@@ -0,0 +1,174 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Field that marks documents made by "db seed", so --purge removes only those
+const seedTag = "foldcliSeed"
+
+// Every seeded user has this password
+const seedPassword = "Seeded-password1!"
+
+var seedUsers, seedAdmins int
+var seedValue int64
+var seedPurge, seedForce bool
+
+var seedNames = []string{
+	"alice", "bob", "carol", "dave", "erin", "frank", "grace", "heidi", "ivan", "judy",
+	"mallory", "niaj", "olivia", "peggy", "rupert", "sybil", "trent", "victor", "walter", "yuki",
+}
+
+var seedNotifications = []struct{ title, notify string }{
+	{"Welcome to Folderr", "Your account is ready. Upload something!"},
+	{"Maintenance", "Folderr will be down for maintenance this weekend."},
+	{"Storage", "You're using most of your storage."},
+	{"New feature", "Links can now be shortened from the dashboard."},
+}
+
+type seededUser struct {
+	User   `bson:",inline"`
+	Seeded bool `bson:"foldcliSeed"`
+}
+
+// Makes a version 4 UUID from rng, so the same seed makes the same IDs
+func seededUUID(rng *rand.Rand) string {
+	b := make([]byte, 16)
+	rng.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Generates count fake users. Usernames & emails are unique within a seed
+func seedUserDocuments(rng *rand.Rand, count int, admins int, hashed string, now time.Time) []interface{} {
+	docs := []interface{}{}
+	for i := 0; i < count+admins; i++ {
+		name := seedNames[rng.Intn(len(seedNames))]
+		// The index keeps usernames unique, they stay within the default 16 characters
+		username := fmt.Sprintf("%v_%v", name, i+1)
+		createdAt := now.Add(-time.Duration(rng.Int63n(int64(365 * 24 * time.Hour)))).Truncate(time.Millisecond)
+		notifs := []Notification{}
+		for n := rng.Intn(4); n > 0; n-- {
+			notification := seedNotifications[rng.Intn(len(seedNotifications))]
+			notifs = append(notifs, Notification{
+				Id:        seededUUID(rng),
+				Title:     notification.title,
+				Notify:    notification.notify,
+				CreatedAt: createdAt.Add(time.Duration(rng.Int63n(int64(now.Sub(createdAt))))).Truncate(time.Millisecond),
+			})
+		}
+		docs = append(docs, seededUser{
+			User: User{
+				Id:        seededUUID(rng),
+				Username:  username,
+				Email:     username + "@example.com",
+				Password:  hashed,
+				Admin:     i >= count,
+				CURLs:     []string{},
+				Files:     rng.Intn(500),
+				Links:     rng.Intn(100),
+				Notifs:    notifs,
+				CreatedAt: createdAt,
+				Privacy:   UserPrivacy{DataCollection: rng.Intn(2) == 0},
+			},
+			Seeded: true,
+		})
+	}
+	return docs
+}
+
+var dbSeedCmd = &cobra.Command{
+	Use:   "seed",
+	Short: "Fill the database with fake users, for development",
+	Long: `Fill the database with fake users, for development.
+Users get UUIDs, notifications, files & links counts and creation dates over the past year.
+The same --seed makes the same users. Every seeded user has the password "` + seedPassword + `".
+Seeded users are tagged, "` + utilities.Constants.RootCmdName + ` db seed --purge" removes only them.
+Refuses to seed a database with real users unless --force is given`,
+	Example: "  " + utilities.Constants.RootCmdName + " db seed --users 200 --admins 5\n  " +
+		utilities.Constants.RootCmdName + " db seed --purge",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !seedPurge && (seedUsers < 0 || seedAdmins < 0 || seedUsers+seedAdmins == 0) {
+			return errors.New("choose how many users to make with --users and --admins")
+		}
+		client, coll, err := usersCollection()
+		if err != nil {
+			return err
+		}
+		defer disconnectDB(client)
+		ctx := context.TODO()
+		seeded := bson.D{{Key: seedTag, Value: true}}
+
+		if seedPurge {
+			count, err := coll.CountDocuments(ctx, seeded)
+			if err != nil {
+				return utilities.WrapMongoError(err)
+			}
+			if dry {
+				cmd.Printf("Would remove %v seeded users\nNOTICE: Did NOT remove them, due to dry run\n", count)
+				return nil
+			}
+			result, err := coll.DeleteMany(ctx, seeded)
+			if err != nil {
+				return utilities.WrapMongoError(err)
+			}
+			cmd.Printf("Removed %v seeded users\n", result.DeletedCount)
+			return nil
+		}
+
+		realUsers, err := coll.CountDocuments(ctx, bson.D{{Key: seedTag, Value: bson.D{{Key: "$ne", Value: true}}}, {Key: "owner", Value: false}})
+		if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		if realUsers > 0 && !seedForce {
+			return fmt.Errorf("the database has %v real users, it may not be a development database. Use --force to seed it anyway", realUsers)
+		}
+		if dry {
+			cmd.Printf("Would add %v users and %v admins\nNOTICE: Did NOT save, due to dry run\n", seedUsers, seedAdmins)
+			return nil
+		}
+
+		// Hashed once, hashing each user's password takes too long for large seeds
+		hashed, err := hashPassword(seedPassword)
+		if err != nil {
+			return err
+		}
+		rng := rand.New(rand.NewSource(seedValue))
+		docs := seedUserDocuments(rng, seedUsers, seedAdmins, hashed, time.Now())
+		_, err = coll.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+		inserted := len(docs)
+		var bulkErr mongo.BulkWriteException
+		if errors.As(err, &bulkErr) && utilities.ClassifyMongoError(err) == utilities.MongoDuplicateKey {
+			inserted -= len(bulkErr.WriteErrors)
+			cmd.Printf("Skipped %v users whose username or email exists, use another --seed or --purge first\n", len(bulkErr.WriteErrors))
+		} else if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		cmd.Printf("Added %v seeded users with the password %q (seed %v)\n", inserted, seedPassword, seedValue)
+		return nil
+	},
+}
+
+func init() {
+	dbSeedCmd.Flags().IntVar(&seedUsers, "users", 0, "How many users to add")
+	dbSeedCmd.Flags().IntVar(&seedAdmins, "admins", 0, "How many admins to add")
+	dbSeedCmd.Flags().Int64Var(&seedValue, "seed", 1, "Random seed. The same seed makes the same users")
+	dbSeedCmd.Flags().BoolVar(&seedPurge, "purge", false, "Remove every seeded user")
+	dbSeedCmd.Flags().BoolVar(&seedForce, "force", false, "Seed even if the database has real users")
+	dbCmd.AddCommand(dbSeedCmd)
+}
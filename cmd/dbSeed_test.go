@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Folderr/foldcli/utilities"
+)
+
+func TestSeedUserDocumentsAreReproducible(t *testing.T) {
+	now := time.Now()
+	first := seedUserDocuments(rand.New(rand.NewSource(42)), 20, 2, "hash", now)
+	second := seedUserDocuments(rand.New(rand.NewSource(42)), 20, 2, "hash", now)
+	if !reflect.DeepEqual(first, second) {
+		t.Error("Expected the same seed to make the same users")
+	}
+
+	policy := utilities.DefaultPolicy()
+	admins := 0
+	for _, doc := range first {
+		user := doc.(seededUser)
+		if err := policy.Username.Check(user.Username); err != nil {
+			t.Errorf("Seeded username %q is invalid: %v", user.Username, err)
+		}
+		if err := utilities.ValidateEmail(user.Email); err != nil {
+			t.Errorf("Seeded email %q is invalid: %v", user.Email, err)
+		}
+		if user.Admin {
+			admins++
+		}
+	}
+	if len(first) != 22 || admins != 2 {
+		t.Errorf("Expected 20 users and 2 admins, got %v users and %v admins", len(first), admins)
+	}
+}
@@ -43,6 +43,8 @@ var installFolderr = &cobra.Command{
 	Long:  `Checks for Folderrs dependencies and installs Folderr`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var config utilities.Config
+		var secrets utilities.SecretConfig
+		var vip *viper.Viper
 		if sharedConfig.Directory != "" {
 			cmd.Println("Shared config directory not found")
 			config = sharedConfig
@@ -51,7 +53,7 @@ var installFolderr = &cobra.Command{
 			if err != nil {
 				return err
 			}
-			_, config, _, err = utilities.ReadConfig(dir, dry)
+			vip, config, secrets, err = utilities.ReadConfig(dir, dry)
 			if err != nil {
 				panic(err)
 			}
@@ -146,10 +148,14 @@ var installFolderr = &cobra.Command{
 		gitOptions := &git.CloneOptions{
 			URL: config.Repository,
 		}
-		if authFlag != "" {
+		token := authFlag
+		if token == "" {
+			token = secrets.GitToken
+		}
+		if token != "" {
 			gitOptions.Auth = &transport.BasicAuth{
 				Username: "git",
-				Password: authFlag,
+				Password: token,
 			}
 		}
 
@@ -160,7 +166,7 @@ var installFolderr = &cobra.Command{
 				cmd.Println("That repository doesn't exist")
 				os.Exit(1)
 			} else if strings.Contains(err.Error(), "authorization") || strings.Contains(err.Error(), "authentication") {
-				cmd.Println("Authentication required. Please pass either the authorization flag or set the " + utilities.Constants.EnvPrefix + "TOKEN environment variable.\n" +
+				cmd.Println("Authentication required. Please pass the authorization flag, set the " + utilities.Constants.EnvPrefix + "GIT_TOKEN environment variable or save the \"" + utilities.SecretGitToken + "\" secret.\n" +
 					"See \"" + utilities.Constants.RootCmdName + " install --help\" for more info")
 				os.Exit(1)
 			} else {
@@ -187,14 +193,18 @@ var installFolderr = &cobra.Command{
 		if highestVer == nil {
 			cmd.Println("Not using Tags for updating...")
 			cmd.Println("Reason: Latest tag is too old. (Pre V2)")
-			viper.Set("releaseType", "commit")
+			releaseType = "commit"
 		} else {
 			releaseType = "tag"
-			viper.Set("releaseType", "tag")
-			viper.Set("release", highest.Name().Short())
 		}
-		if !dry {
-			err = viper.WriteConfig()
+		if vip != nil {
+			vip.Set("releaseType", releaseType)
+			if releaseType == "tag" {
+				vip.Set("release", highest.Name().Short())
+			}
+		}
+		if !dry && vip != nil {
+			err = vip.WriteConfig()
 			if err != nil {
 				cmd.Println("Error Occurred while writing config:", err)
 				panic(err)
@@ -12,13 +12,39 @@ import (
 )
 
 var keygenOverride = false
+var keyAlgorithm, keyFormat string
+var keyBits int
+
+// Adds the --algorithm, --bits & --format flags of commands that generate keys
+func addKeyFlags(command *cobra.Command) {
+	command.Flags().StringVar(&keyAlgorithm, "algorithm", string(utilities.DefaultKeyOptions.Algorithm), "Key algorithm: "+strings.Join(utilities.KeyAlgorithms, ", "))
+	command.Flags().IntVar(&keyBits, "bits", 0, "RSA key size (default 2048) or ECDSA curve: 256, 384 or 521 (default 256)")
+	command.Flags().StringVar(&keyFormat, "format", string(utilities.DefaultKeyOptions.Format), "Key encoding: "+strings.Join(utilities.KeyFormats, ", "))
+	command.RegisterFlagCompletionFunc("algorithm", cobra.FixedCompletions(utilities.KeyAlgorithms, cobra.ShellCompDirectiveNoFileComp))
+	command.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(utilities.KeyFormats, cobra.ShellCompDirectiveNoFileComp))
+}
+
+func keyOptions() utilities.KeyOptions {
+	return utilities.KeyOptions{
+		Algorithm: utilities.KeyAlgorithm(strings.ToLower(keyAlgorithm)),
+		Bits:      keyBits,
+		Format:    utilities.KeyFormat(strings.ToLower(keyFormat)),
+	}
+}
 
 var keygenCmd = &cobra.Command{
-	Use:     "keygen <path_for_private_key> <path_for_public_key>",
-	Short:   "Generate a private/public keypair according to Folderr's standards",
-	Example: "foldcli keygen /home/fldrr/keys/private.pem /home/fldrr/keys/public.pem",
+	Use:   "keygen <path_for_private_key> <path_for_public_key>",
+	Short: "Generate a private/public keypair according to Folderr's standards",
+	Long: `Generate a private/public keypair according to Folderr's standards.
+Formats:
+  pkcs1: "RSA PRIVATE KEY" & "RSA PUBLIC KEY", RSA only
+  pkcs8: "PRIVATE KEY" & "PUBLIC KEY" (PKIX)
+  pkix:  "RSA PRIVATE KEY" or "EC PRIVATE KEY" & "PUBLIC KEY" (PKIX)
+Ed25519 keys can be made, but Folderr can't sign logins with them`,
+	Example: "foldcli keygen /home/fldrr/keys/private.pem /home/fldrr/keys/public.pem\n" +
+		"foldcli keygen --algorithm ecdsa --bits 384 /home/fldrr/keys/private.pem /home/fldrr/keys/public.pem",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		privKey, pubKey, err := utilities.GenKeys()
+		privKey, pubKey, err := utilities.GenKeysWithOptions(keyOptions())
 		if err != nil {
 			return err
 		}
@@ -37,6 +63,9 @@ var keygenCmd = &cobra.Command{
 		if len(args) < 2 {
 			return errors.New("please provide the private key and public key paths")
 		}
+		if err := keyOptions().Validate(); err != nil {
+			return err
+		}
 		pubDir := filepath.Dir(args[1])
 		privDir := filepath.Dir(args[0])
 
@@ -98,5 +127,6 @@ var keygenCmd = &cobra.Command{
 
 func init() {
 	keygenCmd.Flags().BoolVarP(&keygenOverride, "force", "f", false, "Override the current keys, if they eixst")
+	addKeyFlags(keygenCmd)
 	RootCmd.AddCommand(keygenCmd)
 }
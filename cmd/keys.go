@@ -0,0 +1,45 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package cmd
+
+import (
+	"errors"
+	"path/filepath"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/spf13/cobra"
+)
+
+// keysCmd represents the keys command
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Manage the keys Folderr signs logins with",
+	Long: `Manage the keys Folderr signs logins (JWTs) with.
+The private key lives in Folderr's "internal/keys" directory, the public key in the database
+and copies of both in the CLI's keys directory`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(keysCmd)
+}
+
+// Where the CLI keeps copies of Folderr's keys
+func cliKeysDir() (string, error) {
+	dir, err := utilities.GetConfigDir(dry)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "keys"), nil
+}
+
+// The keys commands write to Folderr's directory
+func requireFolderrDir(config utilities.Config) error {
+	if !utilities.CheckInitialization(&config).Folderr {
+		return errors.New("please run \"" + rootCmdName + " init folderr\" before running this command. thanks")
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var rotateKeepPrevious bool
+var rotateGrace time.Duration
+
+// Copies the file at src into dir as name, if it exists
+func backupFile(src string, dir string, name string) error {
+	contents, err := os.ReadFile(src)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name), contents, 0600)
+}
+
+var keysRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Replace Folderr's keys with a new pair",
+	Long: `Replace Folderr's keys with a new pair.
+The old keys are backed up in the CLI's keys directory under "backups", the new private key is written
+to Folderr's "internal/keys" and the new public key replaces the one in the database.
+With --keep-previous the old public key stays in the database (as previousPublicKeyJWT) for the grace period,
+so logins from before the rotation keep working until they expire.
+Restart Folderr afterwards`,
+	Example: "  " + utilities.Constants.RootCmdName + " keys rotate\n  " +
+		utilities.Constants.RootCmdName + " keys rotate --keep-previous --grace 336h",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := keyOptions().CheckFolderrCompatible(); err != nil {
+			return err
+		}
+		if rotateGrace < 0 {
+			return fmt.Errorf("--grace can't be negative, got %v", rotateGrace)
+		}
+		ctx := context.TODO()
+		config, client, coll, doc, err := findFolderrDocument(ctx)
+		if err != nil {
+			return err
+		}
+		defer disconnectDB(client)
+		if err = requireFolderrDir(config); err != nil {
+			return err
+		}
+		oldPublic, err := publicKeyPem(doc.PublicKeyJWT)
+		if err != nil && rotateKeepPrevious {
+			return fmt.Errorf("can't keep the previous public key: %w", err)
+		}
+
+		keysDir, err := cliKeysDir()
+		if err != nil {
+			return err
+		}
+		folderrKey := filepath.Join(config.Directory, "internal/keys/privateJWT.pem")
+		backupDir := filepath.Join(keysDir, "backups", time.Now().Format("20060102-150405"))
+		if dry {
+			cmd.Println("Would back up the keys to", backupDir)
+			cmd.Println("Would write the new private key to", folderrKey, "and", keysDir)
+			cmd.Println("Would replace the public key in the database")
+			cmd.Println("NOTICE: Did NOT rotate the keys, due to dry run")
+			return nil
+		}
+
+		privatePem, publicPem, err := utilities.GenKeysWithOptions(keyOptions())
+		if err != nil {
+			return err
+		}
+
+		err = os.MkdirAll(backupDir, 0700)
+		if err != nil {
+			return err
+		}
+		backups := []struct{ src, name string }{
+			{filepath.Join(keysDir, "privateJWT.pem"), "privateJWT.pem"},
+			{filepath.Join(keysDir, "publicJWT.pem"), "publicJWT.pem"},
+			{folderrKey, "folderr-privateJWT.pem"},
+			{filepath.Join(config.Directory, "internal/locations.json"), "folderr-locations.json"},
+		}
+		for _, backup := range backups {
+			if err = backupFile(backup.src, backupDir, backup.name); err != nil {
+				return fmt.Errorf("failed to back up %v, nothing was changed: %w", backup.src, err)
+			}
+		}
+		if oldPublic != nil {
+			err = os.WriteFile(filepath.Join(backupDir, "database-publicJWT.pem"), oldPublic, 0600)
+			if err != nil {
+				return fmt.Errorf("failed to back up the public key from the database, nothing was changed: %w", err)
+			}
+		}
+		cmd.Println("Backed up the old keys to", backupDir)
+
+		// Every change is journaled, so a failure puts the old keys back everywhere
+		journal := &utilities.Journal{}
+		fail := func(err error) error {
+			cmd.Println("Rotating the keys failed, Folderr still uses the old keys")
+			cmd.Println(utilities.DescribeRollback(journal.Rollback()))
+			cmd.Println("The old keys are also backed up in", backupDir)
+			return err
+		}
+
+		err = journal.WriteFile(filepath.Join(keysDir, "privateJWT.pem"), privatePem, 0600)
+		if err == nil {
+			err = journal.WriteFile(filepath.Join(keysDir, "publicJWT.pem"), publicPem, 0600)
+		}
+		if err != nil {
+			return fail(fmt.Errorf("failed to write the new keys to %v: %w", keysDir, err))
+		}
+		err = saveKeyToFolderr(journal, keysDir, config, privatePem)
+		if err != nil {
+			return fail(err)
+		}
+
+		update := bson.D{{Key: "publicKeyJWT", Value: publicPem}}
+		if rotateKeepPrevious {
+			update = append(update,
+				bson.E{Key: "previousPublicKeyJWT", Value: oldPublic},
+				bson.E{Key: "previousPublicKeyExpiresAt", Value: time.Now().Add(rotateGrace)},
+			)
+		}
+		changes := bson.D{{Key: "$set", Value: update}}
+		if !rotateKeepPrevious {
+			changes = append(changes, bson.E{Key: "$unset", Value: bson.D{
+				{Key: "previousPublicKeyJWT", Value: ""},
+				{Key: "previousPublicKeyExpiresAt", Value: ""},
+			}})
+		}
+		_, err = coll.UpdateOne(ctx, bson.D{{Key: "_id", Value: doc.Id}}, changes)
+		if err != nil {
+			return fail(fmt.Errorf("failed to save the new public key\n%w", utilities.WrapMongoError(err)))
+		}
+
+		cmd.Println("Rotated Folderr's keys. Restart Folderr to use them")
+		if rotateKeepPrevious {
+			cmd.Println("The previous public key is kept until", time.Now().Add(rotateGrace).Format(userTimeFormat))
+		}
+		return nil
+	},
+}
+
+func init() {
+	keysRotateCmd.Flags().BoolVar(&rotateKeepPrevious, "keep-previous", false, "Keep the previous public key so older logins stay valid")
+	keysRotateCmd.Flags().DurationVar(&rotateGrace, "grace", 14*24*time.Hour, "How long the previous public key is kept with --keep-previous")
+	addKeyFlags(keysRotateCmd)
+	keysCmd.AddCommand(keysRotateCmd)
+}
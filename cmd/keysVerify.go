@@ -0,0 +1,205 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package cmd
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/spf13/cobra"
+)
+
+var verifyKeysDir string
+
+// A key found somewhere, or why it couldn't be loaded
+type loadedKey struct {
+	name        string
+	public      crypto.PublicKey
+	private     crypto.Signer
+	err         error
+	missing     bool
+	fingerprint string
+}
+
+func (k *loadedKey) describe() string {
+	if k.missing {
+		return "missing"
+	}
+	if k.err != nil {
+		return "unreadable: " + k.err.Error()
+	}
+	return utilities.DescribeKey(k.public) + " " + k.fingerprint
+}
+
+func loadPrivateKeyFile(name string, path string) *loadedKey {
+	key := &loadedKey{name: name + " (" + path + ")"}
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		key.missing = true
+		return key
+	}
+	if err == nil {
+		key.private, err = utilities.ParsePrivateKeyPEM(contents)
+	}
+	if err == nil {
+		key.public = key.private.Public()
+		key.fingerprint, err = utilities.KeyFingerprint(key.public)
+	}
+	key.err = err
+	return key
+}
+
+func loadPublicKey(name string, contents []byte, err error) *loadedKey {
+	key := &loadedKey{name: name}
+	if errors.Is(err, fs.ErrNotExist) {
+		key.missing = true
+		return key
+	}
+	if err == nil {
+		key.public, err = utilities.ParsePublicKeyPEM(contents)
+	}
+	if err == nil {
+		key.fingerprint, err = utilities.KeyFingerprint(key.public)
+	}
+	key.err = err
+	return key
+}
+
+type keyCheck struct {
+	name    string
+	problem string
+	// Problems with optional copies don't fail the check
+	warning bool
+}
+
+// Checks privateKey signs what publicKey verifies
+func checkKeyPair(name string, privateKey *loadedKey, publicKey *loadedKey, optional bool) keyCheck {
+	check := keyCheck{name: name, warning: optional}
+	switch {
+	case privateKey.missing || privateKey.err != nil:
+		check.problem = privateKey.name + " is " + privateKey.describe()
+	case publicKey.missing || publicKey.err != nil:
+		check.problem = publicKey.name + " is " + publicKey.describe()
+	default:
+		if err := utilities.VerifyKeyPair(privateKey.private, publicKey.public); err != nil {
+			check.warning = false
+			check.problem = fmt.Sprintf(
+				"they are not a pair (%v)\n    %v: %v\n    %v: %v",
+				err,
+				privateKey.name,
+				privateKey.describe(),
+				publicKey.name,
+				publicKey.describe(),
+			)
+		}
+	}
+	return check
+}
+
+var keysVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check Folderr's keys match everywhere",
+	Long: `Check Folderr's keys match everywhere they're kept:
+  Folderr's private key in "internal/keys/privateJWT.pem"
+  the public key in the database
+  the copies in the CLI's keys directory
+Keys are checked by signing and verifying a test payload.
+Also checks Folderr's "internal/locations.json" says the key is configured`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, client, _, doc, err := findFolderrDocument(context.TODO())
+		if err != nil {
+			return err
+		}
+		disconnectDB(client)
+		if err = requireFolderrDir(config); err != nil {
+			return err
+		}
+		keysDir := verifyKeysDir
+		if keysDir == "" {
+			keysDir, err = cliKeysDir()
+			if err != nil {
+				return err
+			}
+		}
+
+		folderrPrivate := loadPrivateKeyFile("Folderr's private key", filepath.Join(config.Directory, "internal/keys/privateJWT.pem"))
+		databasePem, err := publicKeyPem(doc.PublicKeyJWT)
+		databasePublic := loadPublicKey("the database's public key", databasePem, err)
+		cliPrivate := loadPrivateKeyFile("the CLI's private key", filepath.Join(keysDir, "privateJWT.pem"))
+		cliPublicPem, err := os.ReadFile(filepath.Join(keysDir, "publicJWT.pem"))
+		cliPublic := loadPublicKey("the CLI's public key ("+filepath.Join(keysDir, "publicJWT.pem")+")", cliPublicPem, err)
+
+		checks := []keyCheck{
+			checkKeyPair("Folderr's private key & the database's public key", folderrPrivate, databasePublic, false),
+			checkKeyPair("CLI private key copy & the database's public key", cliPrivate, databasePublic, true),
+			checkKeyPair("Folderr's private key & CLI public key copy", folderrPrivate, cliPublic, true),
+		}
+
+		locationsCheck := keyCheck{name: "locations.json"}
+		locationsPath := filepath.Join(config.Directory, "internal/locations.json")
+		contents, err := os.ReadFile(locationsPath)
+		locations := locationJSON{}
+		if err == nil {
+			err = json.Unmarshal(contents, &locations)
+		}
+		if err != nil {
+			locationsCheck.problem = fmt.Sprintf("failed to read %v: %v", locationsPath, err)
+		} else if !locations.KeyConfigured || locations.Keys != "internal" {
+			locationsCheck.problem = fmt.Sprintf(
+				"%v has keys %q and keyConfigured %v, expected keys \"internal\" and keyConfigured true",
+				locationsPath,
+				locations.Keys,
+				locations.KeyConfigured,
+			)
+		}
+		checks = append(checks, locationsCheck)
+
+		if len(doc.PreviousPublicKey.Value) > 0 {
+			checks = append(checks, keyCheck{
+				name:    "previous public key",
+				problem: "kept in the database until " + doc.PreviousExpiresAt.Local().Format(userTimeFormat),
+				warning: true,
+			})
+		}
+
+		failed := 0
+		writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		for _, check := range checks {
+			switch {
+			case check.problem == "":
+				fmt.Fprintf(writer, "%v\tok\n", check.name)
+			case check.warning:
+				fmt.Fprintf(writer, "%v\tnote\n", check.name)
+			default:
+				fmt.Fprintf(writer, "%v\tFAILED\n", check.name)
+				failed++
+			}
+		}
+		writer.Flush()
+		for _, check := range checks {
+			if check.problem != "" {
+				cmd.Printf("\n%v: %v\n", check.name, check.problem)
+			}
+		}
+		if failed > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%v key check(s) failed. \"%v keys rotate\" makes a new matching pair", failed, rootCmdName)
+		}
+		return nil
+	},
+}
+
+func init() {
+	keysVerifyCmd.Flags().StringVar(&verifyKeysDir, "keys-dir", "", "Where the CLI's copies of the keys are, if \"setup db\" saved them elsewhere")
+	keysCmd.AddCommand(keysVerifyCmd)
+}
@@ -0,0 +1,181 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Folderr/foldcli/utilities"
+	uuid "github.com/fossoreslp/go-uuid-v4"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var notifyTitle, notifyMessage, notifyUser string
+var notifyAdmins, notifyEveryone bool
+var notifyBatchSize int
+
+// Sends a notification to every user in ids. Each user gets their own notification ID
+func pushNotifications(ctx context.Context, coll *mongo.Collection, ids []string) error {
+	models := []mongo.WriteModel{}
+	for _, id := range ids {
+		notificationId, err := uuid.NewString()
+		if err != nil {
+			return err
+		}
+		notification := Notification{
+			Id:        notificationId,
+			Title:     notifyTitle,
+			Notify:    notifyMessage,
+			CreatedAt: time.Now(),
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: "id", Value: id}}).
+			SetUpdate(bson.D{{Key: "$push", Value: bson.D{{Key: "notifs", Value: notification}}}}))
+	}
+	_, err := coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// Counts the targets chosen by value, so --admins=false doesn't count as a choice
+func notifyTargets() int {
+	targets := 0
+	for _, chosen := range []bool{notifyUser != "", notifyAdmins, notifyEveryone} {
+		if chosen {
+			targets++
+		}
+	}
+	return targets
+}
+
+var notifyCmd = &cobra.Command{
+	Use:   "notify",
+	Short: "Send a notification to users",
+	Long: `Send a notification to one user (--user), every admin (--admins) or everyone (--everyone).
+Users marked for deletion aren't notified.
+With --dry the recipients are counted, but nobody is notified`,
+	Example: "  " + utilities.Constants.RootCmdName + " notify --everyone --title \"Maintenance\" --message \"Folderr is down for maintenance at 10:00 UTC\"\n  " +
+		utilities.Constants.RootCmdName + " notify --user alice --title \"Hi\" --message \"Your account was restored\"",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if notifyTargets() != 1 {
+			return errors.New("choose who to notify with one of --user, --admins or --everyone")
+		}
+		if strings.TrimSpace(notifyTitle) == "" || strings.TrimSpace(notifyMessage) == "" {
+			return errors.New("--title and --message can't be empty")
+		}
+		if notifyBatchSize < 1 {
+			return errors.New("--batch-size must be at least 1")
+		}
+
+		client, coll, err := usersCollection()
+		if err != nil {
+			return err
+		}
+		defer disconnectDB(client)
+		ctx := context.TODO()
+
+		// Migration 2 turns null notifs into arrays, $push fails on null
+		version, err := utilities.SchemaVersion(ctx, coll.Database())
+		if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		if version < 2 {
+			return errors.New("the database needs migrating first. Run \"" + rootCmdName + " db migrate\"")
+		}
+
+		filter := bson.D{{Key: "markedForDeletion", Value: bson.D{{Key: "$ne", Value: true}}}}
+		if notifyUser != "" {
+			user, err := findUser(ctx, coll, notifyUser)
+			if err != nil {
+				return err
+			}
+			if user.MarkedForDeletion {
+				return fmt.Errorf("%v is marked for deletion", user.Username)
+			}
+			filter = append(filter, bson.E{Key: "id", Value: user.Id})
+		} else if notifyAdmins {
+			filter = append(filter, bson.E{Key: "admin", Value: true})
+		}
+
+		total, err := coll.CountDocuments(ctx, filter)
+		if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		if dry {
+			cmd.Printf("Would notify %v user(s)\nNOTICE: Did NOT notify anyone, due to dry run\n", total)
+			return nil
+		}
+		if total == 0 {
+			cmd.Println("Nobody to notify")
+			return nil
+		}
+
+		cursor, err := coll.Find(ctx, filter, options.Find().
+			SetProjection(bson.D{{Key: "id", Value: 1}}).
+			SetSort(bson.D{{Key: "_id", Value: 1}}).
+			SetBatchSize(int32(notifyBatchSize)))
+		if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		defer cursor.Close(ctx)
+
+		notified := 0
+		batch := []string{}
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			if err := pushNotifications(ctx, coll, batch); err != nil {
+				return fmt.Errorf("failed after notifying %v of %v users\n%w", notified, total, utilities.WrapMongoError(err))
+			}
+			notified += len(batch)
+			batch = batch[:0]
+			if total > int64(notifyBatchSize) {
+				cmd.Printf("Notified %v/%v users\n", notified, total)
+			}
+			return nil
+		}
+		for cursor.Next(ctx) {
+			var user struct {
+				Id string `bson:"id"`
+			}
+			if err = cursor.Decode(&user); err != nil {
+				return err
+			}
+			batch = append(batch, user.Id)
+			if len(batch) >= notifyBatchSize {
+				if err = flush(); err != nil {
+					return err
+				}
+			}
+		}
+		if err = cursor.Err(); err != nil {
+			return fmt.Errorf("failed after notifying %v of %v users\n%w", notified, total, utilities.WrapMongoError(err))
+		}
+		if err = flush(); err != nil {
+			return err
+		}
+		cmd.Printf("Sent %q to %v user(s)\n", notifyTitle, notified)
+		return nil
+	},
+}
+
+func init() {
+	notifyCmd.Flags().StringVar(&notifyTitle, "title", "", "Title of the notification")
+	notifyCmd.Flags().StringVar(&notifyMessage, "message", "", "Text of the notification")
+	notifyCmd.MarkFlagRequired("title")
+	notifyCmd.MarkFlagRequired("message")
+	notifyCmd.Flags().StringVar(&notifyUser, "user", "", "Notify one user, by ID, username or email")
+	notifyCmd.Flags().BoolVar(&notifyAdmins, "admins", false, "Notify every admin")
+	notifyCmd.Flags().BoolVar(&notifyEveryone, "everyone", false, "Notify everyone")
+	notifyCmd.Flags().IntVar(&notifyBatchSize, "batch-size", 500, "Users notified per database write")
+	RootCmd.AddCommand(notifyCmd)
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// Never run parallel. It fucks up viper.
+func TestNotifyTargetsCountedByValue(t *testing.T) {
+	defer func() {
+		notifyUser, notifyAdmins, notifyEveryone = "", false, false
+	}()
+	RootCmd.SetOut(&bytes.Buffer{})
+	RootCmd.SetErr(&bytes.Buffer{})
+	RootCmd.SetArgs([]string{"notify", "--admins=false", "--title", "Hi", "--message", "Hello"})
+	err := RootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "choose who to notify") {
+		t.Fatalf("expected --admins=false to not count as a target, got %v", err)
+	}
+
+	notifyUser, notifyAdmins, notifyEveryone = "alice", false, false
+	if notifyTargets() != 1 {
+		t.Errorf("expected --user alice --admins=false to be one target, got %v", notifyTargets())
+	}
+	notifyAdmins = true
+	if notifyTargets() != 2 {
+		t.Errorf("expected --user alice --admins to be two targets, got %v", notifyTargets())
+	}
+}
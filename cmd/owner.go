@@ -9,20 +9,17 @@ import (
 	"encoding/base64"
 	"fmt"
 	"os"
-	"regexp"
-	"strings"
 	"time"
 
 	"github.com/Folderr/foldcli/utilities"
 	"github.com/fossoreslp/go-uuid-v4"
 	"github.com/spf13/cobra"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/crypto/argon2"
 )
 
-var ownerUsername, ownerPassword, ownerEmail string
+var ownerUsername, ownerPassword, ownerEmail, ownerPasswordFile string
+var ownerPasswordStdin, ownerShowPassword bool
 
 type User struct {
 	Id                string         `bson:"id"`
@@ -56,7 +53,10 @@ var ownerCmd = &cobra.Command{
 	Use:   "owner",
 	Short: "Set up the owner for your Folderr instance",
 	Long: `Set's up the owner account on your Folderr instance
+The password is asked for without showing it, unless given with --password-stdin or --password-file.
 Please run "` + utilities.Constants.RootCmdName + ` init db" before running this command`,
+	Example: "  " + utilities.Constants.RootCmdName + " setup owner -u admin -e admin@folderr.net\n  " +
+		"cat password.txt | " + utilities.Constants.RootCmdName + " setup owner -u admin -e admin@folderr.net --password-stdin",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// prechecks
 
@@ -64,64 +64,76 @@ Please run "` + utilities.Constants.RootCmdName + ` init db" before running this
 		if err != nil {
 			panic(err)
 		}
-		_, config, _, err := utilities.ReadConfig(dir, dry)
-		uri := config.Database.Url
-		if uri == "" || config.Database.DbName == "" {
+		_, config, secrets, err := utilities.ReadConfig(dir, dry)
+		if err != nil {
+			return err
+		}
+		if err = utilities.RequireDBSecrets(config, secrets); err != nil {
+			return err
+		}
+		if config.Database.Url == "" || config.Database.DbName == "" {
 			return fmt.Errorf("please run \"" + rootCmdName + " init db\" before running this command. thanks")
 		}
 
-		// P: Puncuation unicode
-		// M: Mark (Accents)
-		// Z: Separator
-		regexfail := false
-		passwdreg := regexp.MustCompile(`(.*[A-Za-z-_])(.*[\p{M}\p{Z}\p{P}]).{8,256}`)
-		usernamereg := regexp.MustCompile(`\w{3,16}`)
-		emailreg := regexp.MustCompile(`([\w.\-$%#!+/=^;&'*]{2,})?@[a-z\d$-_.+!*’(,;:@&=/]{2,}\.[a-z]{2,}(.[a-z]{2,})?`)
-		// check if password is valid
-		if len(passwdreg.FindString(ownerPassword)) != len(ownerPassword) {
-			// TODO: Show password requirements
-			fmt.Println(`Password is invalid
-Password must be between 8 and 256 characters
-Contain one special character
-Contain one number
-And contain a upper or lowercase A-Z letter`)
-			regexfail = true
+		// Counted by value, so "--password-stdin=false" isn't a source
+		sources := 0
+		for _, chosen := range []bool{ownerPassword != "", ownerPasswordStdin, ownerPasswordFile != ""} {
+			if chosen {
+				sources++
+			}
 		}
-
-		// check if username is valid
-		if len(usernamereg.FindString(ownerUsername)) != len(ownerUsername) {
-			fmt.Println(`Username is invalid
-Username must include at least 3 characters of which all uppercase/lowercase alphanumeric numbers are allowed, and so is an underscore
-Max size: 16 characters`)
-			regexfail = true
+		if sources > 1 {
+			return fmt.Errorf("use only one of --password, --password-stdin and --password-file")
+		}
+		if ownerPasswordStdin {
+			ownerPassword, err = readPassword(cmd.InOrStdin())
+		} else if ownerPasswordFile != "" {
+			ownerPassword, err = utilities.ReadSecretFile(ownerPasswordFile)
+		}
+		if err != nil {
+			return err
 		}
 
-		// check if email is valid
-		if len(emailreg.FindString(ownerEmail)) != len(ownerEmail) {
-			fmt.Println(`Email is invalid. Standard email names are allowed.
-If you believe this to be a bug please submit an issue at https://github.com/Folderr/folderr-cli/issues under "bug report"`)
-			regexfail = true
+		policy, err := utilities.LoadPolicy(config.Policy)
+		if err != nil {
+			return err
+		}
+		invalid := false
+		for _, err := range []error{policy.Username.Check(ownerUsername), utilities.ValidateEmail(ownerEmail)} {
+			if err != nil {
+				fmt.Println(err)
+				invalid = true
+			}
+		}
+		// Prompted for once the rest is known to be valid
+		if sources > 0 {
+			if err = policy.Password.Check(ownerPassword); err != nil {
+				fmt.Println(err)
+				invalid = true
+			}
 		}
 
-		if regexfail {
+		if invalid {
 			os.Exit(1)
 		}
 
-		client, err := mongo.Connect(context.TODO(), options.Client().ApplyURI(uri).SetAppName("Folderr CLI"))
-		if err != nil {
-			panic(err)
+		if sources == 0 {
+			ownerPassword, err = promptPassword(cmd, "Owner password", policy.Password)
+			if err != nil {
+				return err
+			}
 		}
 
-		defer func() {
-			if client.Disconnect(context.TODO()) != nil {
-				panic(err)
-			}
-		}()
+		client, err := utilities.NewMongoClient(config.Database, defaultDBTimeout)
+		if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		defer disconnectDB(client)
 
 		coll := client.Database(config.Database.DbName).Collection("users")
 
 		var preUser User
-		coll.FindOne(context.TODO(), bson.D{
+		err = coll.FindOne(context.TODO(), bson.D{
 			{Key: "$or", Value: bson.A{
 				bson.D{
 					{Key: "username", Value: ownerUsername},
@@ -132,6 +144,10 @@ If you believe this to be a bug please submit an issue at https://github.com/Fol
 			},
 			},
 		}).Decode(&preUser)
+		if kind := utilities.ClassifyMongoError(err); kind != utilities.MongoOK && kind != utilities.MongoNotFound {
+			fmt.Println(utilities.DescribeMongoError(err))
+			os.Exit(1)
+		}
 
 		if preUser.Username == ownerUsername {
 			fmt.Println("Username taken")
@@ -169,35 +185,31 @@ If you believe this to be a bug please submit an issue at https://github.com/Fol
 			CreatedAt:         time.Now(),
 			Owner:             true,
 			Admin:             true,
+			CURLs:             []string{},
+			Notifs:            []Notification{},
 			MarkedForDeletion: false,
 		}
 
 		_, err = coll.InsertOne(context.TODO(), ownerUser)
-		if mongo.IsTimeout(err) {
-			println("Server Timeout Error:", err.Error(), "\nThis can mean that the server is offline, you're offline, or there is (at least) a firewall in the way")
-			return nil
-		} else if mongo.IsNetworkError(err) {
-			println("Network Error:", err.Error())
-			return nil
-		} else if err != nil {
-			if strings.Contains(err.Error(), "Unauthorized") || strings.Contains(err.Error(), "unauthorized") {
-				println(
-					"Authorization error. Please provide authentication information in the string, before the host.\n",
-					"Alternatively the error could mean you do not have permissions on this database.\n",
-					"Error:",
-					err.Error(),
-				)
-				return nil
-			}
+		switch utilities.ClassifyMongoError(err) {
+		case utilities.MongoOK:
+		case utilities.MongoDuplicateKey:
+			// Someone signed up between the check above and now
+			fmt.Println("Username or email taken")
+			os.Exit(1)
+		case utilities.MongoTimeout, utilities.MongoNetwork, utilities.MongoAuthentication, utilities.MongoUnauthorized:
+			return fmt.Errorf("failed to create the owner account\n%w", utilities.WrapMongoError(err))
+		default:
 			fmt.Println("Encountered error while uploading your user data")
-			fmt.Println("Please submit issue with template \"bug report\" at https://github.com/Folderr/folderr-cli/issues with the error below")
-			fmt.Println(err)
+			fmt.Println(utilities.DescribeMongoError(err))
 			os.Exit(1)
 		}
 
 		fmt.Println("Generated owner account. See info below.")
 		fmt.Println("Account ID:", ownerUser.Id)
-		fmt.Println("Password:", ownerPassword)
+		if ownerShowPassword {
+			fmt.Println("Password:", ownerPassword)
+		}
 		fmt.Println("Email:", ownerUser.Email)
 		fmt.Println("Username:", ownerUser.Username)
 		fmt.Println("Created At:", ownerUser.CreatedAt.Format("Monday January _2 2006 15:04:05"))
@@ -252,8 +264,10 @@ func init() {
 	setupCmd.AddCommand(ownerCmd)
 	ownerCmd.Flags().StringVarP(&ownerUsername, "username", "u", "", "Set's the username of the owner account")
 	ownerCmd.MarkFlagRequired("username")
-	ownerCmd.Flags().StringVarP(&ownerPassword, "password", "p", "", "Set's the password of the owner account")
-	ownerCmd.MarkFlagRequired("password")
+	ownerCmd.Flags().StringVarP(&ownerPassword, "password", "p", "", "Set's the password of the owner account. Shows up in your shell history, prefer the prompt or --password-stdin")
+	ownerCmd.Flags().BoolVar(&ownerPasswordStdin, "password-stdin", false, "Read the password of the owner account from stdin")
+	ownerCmd.Flags().StringVar(&ownerPasswordFile, "password-file", "", "Read the password of the owner account from a file")
+	ownerCmd.Flags().BoolVar(&ownerShowPassword, "show-password", false, "Show the password once the owner account is made")
 	ownerCmd.Flags().StringVarP(&ownerEmail, "email", "e", "", "Set's the email of the owner account")
 	ownerCmd.MarkFlagRequired("email")
 
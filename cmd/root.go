@@ -12,6 +12,7 @@ import (
 )
 
 var dry bool
+var configPath string
 
 var rootCmdName = utilities.Constants.RootCmdName
 
@@ -25,8 +26,14 @@ var RootCmd = &cobra.Command{
 	CompletionOptions: cobra.CompletionOptions{
 		DisableDefaultCmd: true,
 	},
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		utilities.SetConfigPath(configPath)
+	},
 	// Cleanup for dry-run commands
 	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		if !dry {
+			return
+		}
 		dir, err := utilities.GetConfigDir(dry)
 		if err != nil {
 			panic(err)
@@ -67,13 +74,17 @@ func init() {
 	// rootCmd.PersistentFlags().BoolVar(&dry, "dry", false, "Runs the command but does not change ANYTHING")
 	RootCmd.SetVersionTemplate("Folderr CLI (foldcli) version: {{ .Version }}\n")
 	RootCmd.PersistentFlags().BoolVar(&dry, "dry", false, "Runs the command but does not change anything")
+	RootCmd.PersistentFlags().StringVar(
+		&configPath,
+		"config",
+		"",
+		"Config file or directory to use. Also set with "+utilities.Constants.EnvPrefix+"CONFIG (default $XDG_CONFIG_HOME/"+rootCmdName+" or $HOME/.folderr/cli)",
+	)
 	RootCmd.ParseFlags(os.Args)
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.cli.yaml)")
-
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 }
@@ -0,0 +1,48 @@
+package secrets
+
+import (
+	"fmt"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/spf13/cobra"
+)
+
+var getCmd = &cobra.Command{
+	Use:               "get <secret>",
+	Short:             "Print a secret from the secrets file",
+	Example:           "  foldcli secrets get git.token",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeSecretNames,
+	RunE: func(command *cobra.Command, args []string) error {
+		name := args[0]
+		err := checkSecretName(name)
+		if err != nil {
+			return err
+		}
+		dir, _, err := configDir(command)
+		if err != nil {
+			return err
+		}
+		if !utilities.SecretsExist(dir) {
+			return fmt.Errorf("there is no secrets file. Create one with \"%v secrets set\"", utilities.Constants.RootCmdName)
+		}
+		key, err := unlockKey(dir)
+		if err != nil {
+			return err
+		}
+		stored, err := utilities.LoadSecrets(dir, key)
+		if err != nil {
+			return err
+		}
+		value, ok := stored[name]
+		if !ok {
+			return fmt.Errorf("secret %q is not set", name)
+		}
+		command.Println(value)
+		return nil
+	},
+}
+
+func init() {
+	secretsCmd.AddCommand(getCmd)
+}
@@ -0,0 +1,100 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/spf13/cobra"
+)
+
+var newKeyFile string
+
+// Whether path is the secrets.key in the config directory
+func isDefaultKeyFile(dir string, path string) bool {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	defaultPath, err := filepath.Abs(filepath.Join(dir, utilities.SecretsKeyFileName))
+	return err == nil && path == defaultPath
+}
+
+var rotateCmd = &cobra.Command{
+	Use:   "rotate-passphrase",
+	Short: "Re-encrypt the secrets file with a new passphrase or key file",
+	Long: `Re-encrypt the secrets file with a new passphrase or key file
+The new passphrase is prompted for, or read from the ` + utilities.Constants.EnvPrefix + `SECRETS_NEW_PASSPHRASE env variable.
+Pass --new-key-file to switch to a key file instead. It is generated if it doesn't exist.
+The old key file is removed if it's the default ` + utilities.SecretsKeyFileName + ` in the config directory, as it can't unlock the secrets file anymore.
+Other key files are left in place`,
+	Example: "  foldcli secrets rotate-passphrase\n  foldcli secrets rotate-passphrase --new-key-file /etc/foldcli/secrets.key",
+	Args:    cobra.NoArgs,
+	RunE: func(command *cobra.Command, args []string) error {
+		dir, dry, err := configDir(command)
+		if err != nil {
+			return err
+		}
+		if !utilities.SecretsExist(dir) {
+			return fmt.Errorf("there is no secrets file. Create one with \"%v secrets set\"", utilities.Constants.RootCmdName)
+		}
+		oldKey, err := unlockKey(dir)
+		if err != nil {
+			return err
+		}
+		stored, err := utilities.LoadSecrets(dir, oldKey)
+		if err != nil {
+			return err
+		}
+
+		var key utilities.SecretsKey
+		if newKeyFile != "" {
+			key, err = newKey(command, dir, newKeyFile, dry)
+		} else if passphrase := os.Getenv(utilities.Constants.EnvPrefix + "SECRETS_NEW_PASSPHRASE"); passphrase != "" {
+			key = utilities.SecretsKey{Passphrase: passphrase}
+		} else {
+			var passphrase string
+			passphrase, err = promptPassphrase("New secrets passphrase", true)
+			key = utilities.SecretsKey{Passphrase: passphrase}
+		}
+		if err != nil {
+			return err
+		}
+
+		if dry {
+			command.Println("Re-encrypted the secrets file\nNOTICE: Did NOT save, due to dry run")
+			return nil
+		}
+		err = utilities.SaveSecrets(dir, key, stored)
+		if err != nil {
+			return err
+		}
+		command.Println("Re-encrypted the secrets file")
+		if oldKey.KeyFile == "" || oldKey.KeyFile == key.KeyFile {
+			return nil
+		}
+		if !isDefaultKeyFile(dir, oldKey.KeyFile) {
+			// Not ours to delete, it may be used for something else
+			command.Println("The old key file", oldKey.KeyFile, "no longer unlocks the secrets file, it was left in place")
+			if os.Getenv(utilities.Constants.EnvPrefix+"SECRETS_KEY_FILE") == oldKey.KeyFile {
+				command.Println("Unset " + utilities.Constants.EnvPrefix + "SECRETS_KEY_FILE, it points at the old key file")
+			}
+			return nil
+		}
+		// The default key file is tried before passphrases, a stale one would lock every command out
+		err = os.Remove(oldKey.KeyFile)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove the old key file %q, remove it yourself as it no longer unlocks the secrets file: %w", oldKey.KeyFile, err)
+		}
+		command.Println("Removed the old key file", oldKey.KeyFile)
+		return nil
+	},
+}
+
+func init() {
+	rotateCmd.Flags().StringVar(&newKeyFile, "new-key-file", "", "Key file to lock the secrets file with from now on")
+	secretsCmd.AddCommand(rotateCmd)
+}
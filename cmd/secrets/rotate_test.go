@@ -0,0 +1,18 @@
+package secrets
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Folderr/foldcli/utilities"
+)
+
+func TestIsDefaultKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	if !isDefaultKeyFile(dir, filepath.Join(dir, "sub", "..", utilities.SecretsKeyFileName)) {
+		t.Error("expected the secrets.key in the config directory to be the default")
+	}
+	if isDefaultKeyFile(dir, filepath.Join(t.TempDir(), utilities.SecretsKeyFileName)) {
+		t.Error("expected a secrets.key in another directory not to be the default")
+	}
+}
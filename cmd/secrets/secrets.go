@@ -0,0 +1,128 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Folderr/foldcli/cmd"
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+var keyFile string
+
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manage the encrypted secrets file",
+	Long: `Manage the encrypted secrets file, which keeps the database URI and git token out of the config
+Secrets: ` + strings.Join(utilities.SecretNames, ", ") + `
+
+The secrets file is unlocked with a passphrase or a key file, in order of priority:
+  the --key-file flag, the ` + utilities.Constants.EnvPrefix + `SECRETS_KEY_FILE env variable,
+  the ` + utilities.Constants.EnvPrefix + `SECRETS_PASSPHRASE(_FILE) env variable, "` + utilities.SecretsKeyFileName + `" in the config directory
+  or a passphrase prompt`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func init() {
+	secretsCmd.PersistentFlags().StringVar(&keyFile, "key-file", "", "Key file that unlocks the secrets file")
+	cmd.RootCmd.AddCommand(secretsCmd)
+}
+
+func configDir(command *cobra.Command) (string, bool, error) {
+	dry, err := command.Flags().GetBool("dry")
+	if err != nil {
+		return "", false, fmt.Errorf("unexpected error: %w", err)
+	}
+	dir, err := utilities.GetConfigDir(dry)
+	return dir, dry, err
+}
+
+func promptPassphrase(label string, confirm bool) (string, error) {
+	prompt := promptui.Prompt{
+		Label: label,
+		Mask:  '*',
+		Validate: func(input string) error {
+			if len(input) < 8 {
+				return errors.New("passphrase must be at least 8 characters")
+			}
+			return nil
+		},
+	}
+	passphrase, err := prompt.Run()
+	if err != nil || !confirm {
+		return passphrase, err
+	}
+	confirmPrompt := promptui.Prompt{
+		Label: "Confirm " + strings.ToLower(label),
+		Mask:  '*',
+		Validate: func(input string) error {
+			if input != passphrase {
+				return errors.New("passphrases do not match")
+			}
+			return nil
+		},
+	}
+	_, err = confirmPrompt.Run()
+	return passphrase, err
+}
+
+// Finds what unlocks an existing secrets file, asking for the passphrase if nothing else does
+func unlockKey(dir string) (utilities.SecretsKey, error) {
+	if keyFile != "" {
+		return utilities.SecretsKey{KeyFile: keyFile}, nil
+	}
+	if key, ok, err := utilities.DefaultSecretsKey(dir); ok || err != nil {
+		return key, err
+	}
+	passphrase, err := promptPassphrase("Secrets passphrase", false)
+	return utilities.SecretsKey{Passphrase: passphrase}, err
+}
+
+// Chooses what will lock a new secrets file.
+// A key file passed with --key-file is generated if it doesn't exist yet
+func newKey(command *cobra.Command, dir string, path string, dry bool) (utilities.SecretsKey, error) {
+	if path == "" {
+		if key, ok, err := utilities.DefaultSecretsKey(dir); ok || err != nil {
+			return key, err
+		}
+		passphrase, err := promptPassphrase("New secrets passphrase", true)
+		return utilities.SecretsKey{Passphrase: passphrase}, err
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if dry {
+			command.Println("Would generate key file", path)
+			return utilities.SecretsKey{KeyFile: path}, nil
+		}
+		err = utilities.GenerateSecretsKeyFile(path)
+		if err != nil {
+			return utilities.SecretsKey{}, err
+		}
+		command.Println("Generated key file", path, "- keep it safe, the secrets can't be read without it")
+	} else if err != nil {
+		return utilities.SecretsKey{}, err
+	}
+	return utilities.SecretsKey{KeyFile: path}, nil
+}
+
+func checkSecretName(name string) error {
+	if !utilities.IsSecretName(name) {
+		return fmt.Errorf("unknown secret %q. Known secrets: %v", name, strings.Join(utilities.SecretNames, ", "))
+	}
+	return nil
+}
+
+func completeSecretNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return utilities.SecretNames, cobra.ShellCompDirectiveNoFileComp
+}
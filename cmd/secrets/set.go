@@ -0,0 +1,107 @@
+package secrets
+
+import (
+	"net/url"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+var setCmd = &cobra.Command{
+	Use:   "set <secret> [value]",
+	Short: "Save a secret in the secrets file",
+	Long: `Save a secret in the secrets file, creating the file if needed
+Leave out the value to be prompted for it, so it stays out of your shell history.
+Setting "` + utilities.SecretDBUrl + `" removes the credentials from the URI in the config file`,
+	Example:           "  foldcli secrets set db.url\n  foldcli secrets set git.token --key-file /etc/foldcli/secrets.key",
+	Args:              cobra.RangeArgs(1, 2),
+	ValidArgsFunction: completeSecretNames,
+	RunE: func(command *cobra.Command, args []string) error {
+		name := args[0]
+		err := checkSecretName(name)
+		if err != nil {
+			return err
+		}
+		dir, dry, err := configDir(command)
+		if err != nil {
+			return err
+		}
+
+		value := ""
+		if len(args) > 1 {
+			value = args[1]
+		} else {
+			prompt := promptui.Prompt{Label: "Value for " + name, Mask: '*'}
+			value, err = prompt.Run()
+			if err != nil {
+				return err
+			}
+		}
+		if name == utilities.SecretDBUrl {
+			err = utilities.ValidateMongoURI(value)
+			if err != nil {
+				return err
+			}
+		}
+
+		var key utilities.SecretsKey
+		stored := map[string]string{}
+		if utilities.SecretsExist(dir) {
+			key, err = unlockKey(dir)
+			if err != nil {
+				return err
+			}
+			stored, err = utilities.LoadSecrets(dir, key)
+			if err != nil {
+				return err
+			}
+		} else {
+			key, err = newKey(command, dir, keyFile, dry)
+			if err != nil {
+				return err
+			}
+		}
+
+		stored[name] = value
+		if dry {
+			command.Println("Saved", name, "\nNOTICE: Did NOT save, due to dry run")
+			return nil
+		}
+		err = utilities.SaveSecrets(dir, key, stored)
+		if err != nil {
+			return err
+		}
+		command.Println("Saved", name, "to", utilities.SecretsPath(dir))
+
+		if name == utilities.SecretDBUrl {
+			return stripConfigCredentials(command, dir)
+		}
+		return nil
+	},
+}
+
+// Removes the user & password from the database URI in the config file, now that the secrets file has them
+func stripConfigCredentials(command *cobra.Command, dir string) error {
+	// Only the file, so env overrides aren't saved with it
+	vip, err := utilities.ReadConfigFile(dir)
+	if err != nil {
+		return err
+	}
+	uri, err := url.Parse(vip.GetString("db.url"))
+	if err != nil || uri.User == nil {
+		return nil
+	}
+	uri.User = nil
+	vip.Set("db.url", uri.String())
+	err = vip.WriteConfig()
+	if err != nil {
+		return err
+	}
+	command.Println("Removed the credentials from db.url in the config file")
+	return nil
+}
+
+func init() {
+	secretsCmd.AddCommand(setCmd)
+}
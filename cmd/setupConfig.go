@@ -0,0 +1,274 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Where Folderr reads its server config from, relative to the Folderr directory
+const folderrServerConfigPath = "configs/server.yaml"
+
+var serverPort int
+var serverUrl, serverUploadLimit, serverConfigFile string
+var serverTrustProxy bool
+
+var byteSizeRegex = regexp.MustCompile(`^(?i)(\d+)\s*(b|kb|mb|gb)?$`)
+
+// Parses sizes like "512MB" into bytes
+func parseByteSize(input string) (int64, error) {
+	match := byteSizeRegex.FindStringSubmatch(strings.TrimSpace(input))
+	if match == nil {
+		return 0, fmt.Errorf("%q is not a size. Use a number of bytes or a size like \"512MB\"", input)
+	}
+	size, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is too big", input)
+	}
+	var unit int64 = 1
+	switch strings.ToLower(match[2]) {
+	case "kb":
+		unit = 1024
+	case "mb":
+		unit = 1024 * 1024
+	case "gb":
+		unit = 1024 * 1024 * 1024
+	}
+	if size > math.MaxInt64/unit {
+		return 0, fmt.Errorf("%q is too big", input)
+	}
+	size *= unit
+	if size <= 0 {
+		return 0, fmt.Errorf("size must be more than 0 bytes")
+	}
+	return size, nil
+}
+
+func validatePort(input string) error {
+	port, err := strconv.Atoi(input)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("port must be a number between 1 and 65535")
+	}
+	return nil
+}
+
+func validatePublicUrl(input string) error {
+	parsed, err := url.Parse(input)
+	if err != nil {
+		return err
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("url must look like \"https://folderr.example.com\"")
+	}
+	return nil
+}
+
+// Current values of the server config, the file's or the defaults
+type serverConfigValues struct {
+	port        string
+	url         string
+	trustProxy  bool
+	uploadLimit string
+}
+
+func currentServerConfig(doc *yaml.Node) serverConfigValues {
+	values := serverConfigValues{port: "8080", url: "http://localhost:8080", uploadLimit: "512MB"}
+	if node := utilities.GetYAMLNode(doc, "port"); node != nil && node.Value != "" {
+		values.port = node.Value
+	}
+	if node := utilities.GetYAMLNode(doc, "url"); node != nil && node.Value != "" {
+		values.url = node.Value
+	}
+	if node := utilities.GetYAMLNode(doc, "trustProxies"); node != nil {
+		values.trustProxy, _ = strconv.ParseBool(node.Value)
+	}
+	if node := utilities.GetYAMLNode(doc, "uploadSizeLimit"); node != nil && node.Value != "" {
+		values.uploadLimit = node.Value
+	}
+	return values
+}
+
+func promptServerConfig(values serverConfigValues) (serverConfigValues, error) {
+	var err error
+	prompt := promptui.Prompt{Label: "Port Folderr listens on", Default: values.port, Validate: validatePort}
+	values.port, err = prompt.Run()
+	if err != nil {
+		return values, err
+	}
+	prompt = promptui.Prompt{Label: "Public URL of your Folderr instance", Default: values.url, Validate: validatePublicUrl}
+	values.url, err = prompt.Run()
+	if err != nil {
+		return values, err
+	}
+	prompt = promptui.Prompt{
+		Label:   "Largest upload allowed (i.e 512MB)",
+		Default: values.uploadLimit,
+		Validate: func(input string) error {
+			_, err := parseByteSize(input)
+			return err
+		},
+	}
+	values.uploadLimit, err = prompt.Run()
+	if err != nil {
+		return values, err
+	}
+	prompt = promptui.Prompt{Label: "Is Folderr behind a reverse proxy (i.e NGINX)", IsConfirm: true}
+	if values.trustProxy {
+		// Enter keeps the saved answer
+		prompt.Default = "y"
+	}
+	_, err = prompt.Run()
+	// promptui aborts with an empty error when the answer is no
+	if err != nil && err.Error() != "" {
+		return values, err
+	}
+	values.trustProxy = err == nil
+	return values, nil
+}
+
+var setupConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Write Folderr's server config",
+	Long: `Write Folderr's server config (port, public url, upload limit, proxy trust & database) to
+"` + folderrServerConfigPath + `" in your Folderr directory.
+Asks for each value unless any of them are passed as flags.
+The database values come from "` + utilities.Constants.RootCmdName + ` init db".
+If the file exists it is updated, keeping any settings this command doesn't know about`,
+	Example: "  " + utilities.Constants.RootCmdName + " setup config\n  " +
+		utilities.Constants.RootCmdName + " setup config --port 8080 --url https://folderr.example.com --trust-proxy",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := utilities.GetConfigDir(dry)
+		if err != nil {
+			return err
+		}
+		_, config, secrets, err := utilities.ReadConfig(dir, dry)
+		if err != nil {
+			return err
+		}
+		// The database URI is written to Folderr's config, it must have its credentials
+		if err = utilities.RequireDBSecrets(config, secrets); err != nil {
+			return err
+		}
+		checkInit := utilities.CheckInitialization(&config)
+		if !checkInit.Folderr {
+			return fmt.Errorf("please run \"" + rootCmdName + " init folderr\" before running this command. thanks")
+		}
+		if !checkInit.Database {
+			return fmt.Errorf("please run \"" + rootCmdName + " init db\" before running this command. thanks")
+		}
+
+		path := serverConfigFile
+		if path == "" {
+			path = filepath.Join(config.Directory, folderrServerConfigPath)
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		existed := err == nil
+		doc, err := utilities.ParseYAMLDocument(contents)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %w", path, err)
+		}
+
+		values := currentServerConfig(doc)
+		flags := cmd.Flags()
+		if flags.Changed("port") || flags.Changed("url") || flags.Changed("trust-proxy") || flags.Changed("upload-limit") {
+			if flags.Changed("port") {
+				values.port = strconv.Itoa(serverPort)
+			}
+			if flags.Changed("url") {
+				values.url = serverUrl
+			}
+			if flags.Changed("trust-proxy") {
+				values.trustProxy = serverTrustProxy
+			}
+			if flags.Changed("upload-limit") {
+				values.uploadLimit = serverUploadLimit
+			}
+		} else {
+			values, err = promptServerConfig(values)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err = validatePort(values.port); err != nil {
+			return err
+		}
+		if err = validatePublicUrl(values.url); err != nil {
+			return err
+		}
+		uploadLimit, err := parseByteSize(values.uploadLimit)
+		if err != nil {
+			return err
+		}
+		port, _ := strconv.Atoi(values.port)
+
+		settings := []struct {
+			path  []string
+			value interface{}
+		}{
+			{[]string{"port"}, port},
+			{[]string{"url"}, strings.TrimSuffix(values.url, "/")},
+			{[]string{"trustProxies"}, values.trustProxy},
+			{[]string{"uploadSizeLimit"}, uploadLimit},
+			{[]string{"db", "url"}, config.Database.Url},
+			{[]string{"db", "dbName"}, config.Database.DbName},
+		}
+		for _, setting := range settings {
+			err = utilities.SetYAMLValue(doc, setting.value, setting.path...)
+			if err != nil {
+				return fmt.Errorf("failed to set %v: %w", strings.Join(setting.path, "."), err)
+			}
+		}
+		output, err := yaml.Marshal(doc)
+		if err != nil {
+			return err
+		}
+
+		if dry {
+			cmd.Println("Wrote Folderr's server config to", path, "\nNOTICE: Did NOT save, due to dry run")
+			return nil
+		}
+		err = os.MkdirAll(filepath.Dir(path), 0700)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(path, output, 0600)
+		if err != nil {
+			return err
+		}
+		if existed {
+			cmd.Println("Updated Folderr's server config at", path)
+		} else {
+			cmd.Println("Wrote Folderr's server config to", path)
+		}
+		return nil
+	},
+}
+
+func init() {
+	setupConfigCmd.Flags().IntVar(&serverPort, "port", 8080, "Port Folderr listens on")
+	setupConfigCmd.Flags().StringVar(&serverUrl, "url", "", "Public URL of your Folderr instance, i.e https://folderr.example.com")
+	setupConfigCmd.Flags().BoolVar(&serverTrustProxy, "trust-proxy", false, "Trust the X-Forwarded-* headers of a reverse proxy")
+	setupConfigCmd.Flags().StringVar(&serverUploadLimit, "upload-limit", "512MB", "Largest upload allowed, i.e 512MB or 1GB")
+	setupConfigCmd.Flags().StringVar(&serverConfigFile, "file", "", "Write to this file instead of \""+folderrServerConfigPath+"\" in your Folderr directory")
+	setupCmd.AddCommand(setupConfigCmd)
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import "testing"
+
+func TestParseByteSize(t *testing.T) {
+	valid := map[string]int64{
+		"512":    512,
+		"10KB":   10 * 1024,
+		"512 mb": 512 * 1024 * 1024,
+		"2GB":    2 * 1024 * 1024 * 1024,
+	}
+	for input, expected := range valid {
+		size, err := parseByteSize(input)
+		if err != nil || size != expected {
+			t.Errorf("%q: expected %v, got %v (%v)", input, expected, size, err)
+		}
+	}
+
+	invalid := []string{"", "0", "-5MB", "5TB", "9223372036854775807GB", "8589934592GB", "99999999999999999999"}
+	for _, input := range invalid {
+		if size, err := parseByteSize(input); err == nil {
+			t.Errorf("%q: expected an error, got %v", input, size)
+		}
+	}
+}
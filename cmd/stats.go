@@ -0,0 +1,233 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var statsOutput, statsInterval string
+var statsTop int
+
+// Date formats ($dateToString) accounts are grouped by
+var statsIntervals = map[string]string{
+	"day":   "%Y-%m-%d",
+	"week":  "%G-W%V",
+	"month": "%Y-%m",
+	"year":  "%Y",
+}
+
+type userCount struct {
+	Username string `bson:"username" json:"username"`
+	Count    int64  `bson:"count" json:"count"`
+}
+
+type periodCount struct {
+	Period string `bson:"_id" json:"period"`
+	Count  int64  `bson:"count" json:"count"`
+}
+
+type instanceStats struct {
+	Users             int64         `bson:"users" json:"users"`
+	Admins            int64         `bson:"admins" json:"admins"`
+	MarkedForDeletion int64         `bson:"markedForDeletion" json:"markedForDeletion"`
+	Files             int64         `bson:"files" json:"files"`
+	Links             int64         `bson:"links" json:"links"`
+	TopFiles          []userCount   `bson:"-" json:"topFiles"`
+	TopLinks          []userCount   `bson:"-" json:"topLinks"`
+	Created           []periodCount `bson:"-" json:"created"`
+	Interval          string        `bson:"-" json:"interval"`
+}
+
+func boolCount(field string) bson.D {
+	return bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{bson.D{{Key: "$eq", Value: bson.A{"$" + field, true}}}, 1, 0}}}}}
+}
+
+func topUsers(field string, limit int) bson.A {
+	return bson.A{
+		bson.D{{Key: "$match", Value: bson.D{{Key: field, Value: bson.D{{Key: "$gt", Value: 0}}}}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: field, Value: -1}, {Key: "username", Value: 1}}}},
+		bson.D{{Key: "$limit", Value: limit}},
+		bson.D{{Key: "$project", Value: bson.D{{Key: "_id", Value: 0}, {Key: "username", Value: 1}, {Key: "count", Value: "$" + field}}}},
+	}
+}
+
+// Aggregates everything in one pass over the users collection
+func statsPipeline(top int, dateFormat string) bson.A {
+	return bson.A{
+		bson.D{{Key: "$facet", Value: bson.D{
+			{Key: "totals", Value: bson.A{
+				bson.D{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: nil},
+					{Key: "users", Value: bson.D{{Key: "$sum", Value: 1}}},
+					{Key: "admins", Value: boolCount("admin")},
+					{Key: "markedForDeletion", Value: boolCount("markedForDeletion")},
+					{Key: "files", Value: bson.D{{Key: "$sum", Value: "$files"}}},
+					{Key: "links", Value: bson.D{{Key: "$sum", Value: "$links"}}},
+				}}},
+			}},
+			{Key: "topFiles", Value: topUsers("files", top)},
+			{Key: "topLinks", Value: topUsers("links", top)},
+			{Key: "created", Value: bson.A{
+				bson.D{{Key: "$match", Value: bson.D{{Key: "createdAt", Value: bson.D{{Key: "$type", Value: "date"}}}}}},
+				bson.D{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: bson.D{{Key: "$dateToString", Value: bson.D{{Key: "format", Value: dateFormat}, {Key: "date", Value: "$createdAt"}}}}},
+					{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+				}}},
+				bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+			}},
+		}}},
+	}
+}
+
+func printStatsTable(w io.Writer, stats instanceStats) error {
+	writer := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(writer, "Users\t%v\n", stats.Users)
+	fmt.Fprintf(writer, "Admins\t%v\n", stats.Admins)
+	fmt.Fprintf(writer, "Marked for deletion\t%v\n", stats.MarkedForDeletion)
+	fmt.Fprintf(writer, "Files\t%v\n", stats.Files)
+	fmt.Fprintf(writer, "Links\t%v\n", stats.Links)
+	for _, top := range []struct {
+		name   string
+		counts []userCount
+	}{{"files", stats.TopFiles}, {"links", stats.TopLinks}} {
+		if len(top.counts) == 0 {
+			continue
+		}
+		fmt.Fprintf(writer, "\nMost %v\t\n", top.name)
+		for _, count := range top.counts {
+			fmt.Fprintf(writer, "  %v\t%v\n", count.Username, count.Count)
+		}
+	}
+	if len(stats.Created) > 0 {
+		fmt.Fprintf(writer, "\nAccounts created per %v\t\n", stats.Interval)
+		for _, count := range stats.Created {
+			fmt.Fprintf(writer, "  %v\t%v\n", count.Period, count.Count)
+		}
+	}
+	return writer.Flush()
+}
+
+var prometheusEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Writes the stats in Prometheus' text format, i.e for node_exporter's textfile collector
+func writePrometheusStats(w io.Writer, stats instanceStats) {
+	gauge := func(name string, help string) {
+		fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v gauge\n", name, help, name)
+	}
+	totals := []struct {
+		name  string
+		help  string
+		value int64
+	}{
+		{"folderr_users", "Users of the instance.", stats.Users},
+		{"folderr_admins", "Admins of the instance.", stats.Admins},
+		{"folderr_users_marked_for_deletion", "Users marked for deletion.", stats.MarkedForDeletion},
+		{"folderr_files", "Files uploaded by every user.", stats.Files},
+		{"folderr_links", "Links shortened by every user.", stats.Links},
+	}
+	for _, total := range totals {
+		gauge(total.name, total.help)
+		fmt.Fprintf(w, "%v %v\n", total.name, total.value)
+	}
+	for _, top := range []struct {
+		name   string
+		help   string
+		counts []userCount
+	}{
+		{"folderr_user_files", "Files uploaded by the users with the most files.", stats.TopFiles},
+		{"folderr_user_links", "Links shortened by the users with the most links.", stats.TopLinks},
+	} {
+		gauge(top.name, top.help)
+		for _, count := range top.counts {
+			fmt.Fprintf(w, "%v{username=\"%v\"} %v\n", top.name, prometheusEscaper.Replace(count.Username), count.Count)
+		}
+	}
+	gauge("folderr_users_created", "Accounts created per "+stats.Interval+".")
+	for _, count := range stats.Created {
+		fmt.Fprintf(w, "folderr_users_created{period=\"%v\"} %v\n", prometheusEscaper.Replace(count.Period), count.Count)
+	}
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show usage statistics of your Folderr instance",
+	Long: `Show usage statistics of your Folderr instance: users, admins, accounts marked for deletion,
+files & links (in total and the users with the most) and accounts created over time.
+Outputs a table, JSON or Prometheus' text format (i.e for node_exporter's textfile collector)`,
+	Example: "  " + utilities.Constants.RootCmdName + " stats\n  " +
+		utilities.Constants.RootCmdName + " stats --top 5 --interval week -o json\n  " +
+		utilities.Constants.RootCmdName + " stats -o prometheus > /var/lib/node_exporter/folderr.prom",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dateFormat, ok := statsIntervals[statsInterval]
+		if !ok {
+			return fmt.Errorf("unknown interval %q, expected day, week, month or year", statsInterval)
+		}
+		if statsOutput != "table" && statsOutput != "json" && statsOutput != "prometheus" {
+			return fmt.Errorf("unknown output %q, expected table, json or prometheus", statsOutput)
+		}
+		if statsTop < 1 {
+			return fmt.Errorf("--top must be at least 1")
+		}
+		client, coll, err := usersCollection()
+		if err != nil {
+			return err
+		}
+		defer disconnectDB(client)
+
+		cursor, err := coll.Aggregate(context.TODO(), statsPipeline(statsTop, dateFormat))
+		if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		results := []struct {
+			Totals   []instanceStats `bson:"totals"`
+			TopFiles []userCount     `bson:"topFiles"`
+			TopLinks []userCount     `bson:"topLinks"`
+			Created  []periodCount   `bson:"created"`
+		}{}
+		if err = cursor.All(context.TODO(), &results); err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		stats := instanceStats{}
+		if len(results) > 0 {
+			if len(results[0].Totals) > 0 {
+				stats = results[0].Totals[0]
+			}
+			stats.TopFiles = results[0].TopFiles
+			stats.TopLinks = results[0].TopLinks
+			stats.Created = results[0].Created
+		}
+		stats.Interval = statsInterval
+
+		switch statsOutput {
+		case "json":
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(stats)
+		case "prometheus":
+			writePrometheusStats(cmd.OutOrStdout(), stats)
+			return nil
+		}
+		return printStatsTable(cmd.OutOrStdout(), stats)
+	},
+}
+
+func init() {
+	statsCmd.Flags().StringVarP(&statsOutput, "output", "o", "table", "Output format: table, json or prometheus")
+	statsCmd.Flags().IntVar(&statsTop, "top", 10, "How many of the users with the most files & links to show")
+	statsCmd.Flags().StringVar(&statsInterval, "interval", "month", "Group account creation by day, week, month or year")
+	statsCmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{"table", "json", "prometheus"}, cobra.ShellCompDirectiveNoFileComp))
+	statsCmd.RegisterFlagCompletionFunc("interval", cobra.FixedCompletions([]string{"day", "week", "month", "year"}, cobra.ShellCompDirectiveNoFileComp))
+	RootCmd.AddCommand(statsCmd)
+}
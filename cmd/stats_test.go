@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWritePrometheusStats(t *testing.T) {
+	stats := instanceStats{
+		Users:    3,
+		Admins:   1,
+		Files:    12,
+		TopFiles: []userCount{{Username: `quote"d`, Count: 10}},
+		Created:  []periodCount{{Period: "2023-05", Count: 3}},
+		Interval: "month",
+	}
+	output := &bytes.Buffer{}
+	writePrometheusStats(output, stats)
+	expected := []string{
+		"# TYPE folderr_users gauge\nfolderr_users 3\n",
+		"folderr_admins 1\n",
+		"folderr_files 12\n",
+		`folderr_user_files{username="quote\"d"} 10` + "\n",
+		`folderr_users_created{period="2023-05"} 3` + "\n",
+	}
+	for _, line := range expected {
+		if !strings.Contains(output.String(), line) {
+			t.Errorf("Expected %q in the output:\n%v", line, output.String())
+		}
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const userTimeFormat = "Monday January _2 2006 15:04:05"
+
+// usersCmd represents the users command
+var usersCmd = &cobra.Command{
+	Use:   "users",
+	Short: "Manage the users of your Folderr instance",
+	Long: `Manage the users of your Folderr instance
+Users are found by their ID, username or email`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(usersCmd)
+}
+
+// A user as shown by the users commands. Leaves out the password hash
+type userJSON struct {
+	Id                string    `json:"id"`
+	Username          string    `json:"username"`
+	Email             string    `json:"email"`
+	Admin             bool      `json:"admin"`
+	Owner             bool      `json:"owner"`
+	Files             int       `json:"files"`
+	Links             int       `json:"links"`
+	Notifications     int       `json:"notifications"`
+	CreatedAt         time.Time `json:"createdAt"`
+	MarkedForDeletion bool      `json:"markedForDeletion"`
+}
+
+func (u User) toJSON() userJSON {
+	return userJSON{
+		Id:                u.Id,
+		Username:          u.Username,
+		Email:             u.Email,
+		Admin:             u.Admin,
+		Owner:             u.Owner,
+		Files:             u.Files,
+		Links:             u.Links,
+		Notifications:     len(u.Notifs),
+		CreatedAt:         u.CreatedAt,
+		MarkedForDeletion: u.MarkedForDeletion,
+	}
+}
+
+// Connects to Folderr's database and returns the users collection
+func usersCollection() (*mongo.Client, *mongo.Collection, error) {
+	config, err := readDBConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := utilities.NewMongoClient(config.Database, defaultDBTimeout)
+	if err != nil {
+		return nil, nil, utilities.WrapMongoError(err)
+	}
+	return client, client.Database(config.Database.DbName).Collection("users"), nil
+}
+
+// Finds a user by ID, username or email
+func findUser(ctx context.Context, coll *mongo.Collection, query string) (User, error) {
+	cursor, err := coll.Find(ctx, bson.D{
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "id", Value: query}},
+			bson.D{{Key: "username", Value: query}},
+			bson.D{{Key: "email", Value: query}},
+		}},
+	}, options.Find().SetLimit(2))
+	if err != nil {
+		return User{}, utilities.WrapMongoError(err)
+	}
+	users := []User{}
+	if err = cursor.All(ctx, &users); err != nil {
+		return User{}, utilities.WrapMongoError(err)
+	}
+	if len(users) == 0 {
+		return User{}, fmt.Errorf("no user has the ID, username or email %q", query)
+	}
+	if len(users) > 1 {
+		return User{}, fmt.Errorf("more than one user matches %q, use their ID instead", query)
+	}
+	return users[0], nil
+}
+
+func printUser(w io.Writer, user User) {
+	writer := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(writer, "Account ID:\t%v\n", user.Id)
+	fmt.Fprintf(writer, "Username:\t%v\n", user.Username)
+	fmt.Fprintf(writer, "Email:\t%v\n", user.Email)
+	fmt.Fprintf(writer, "Admin:\t%v\n", user.Admin)
+	fmt.Fprintf(writer, "Owner:\t%v\n", user.Owner)
+	fmt.Fprintf(writer, "Files:\t%v\n", user.Files)
+	fmt.Fprintf(writer, "Links:\t%v\n", user.Links)
+	fmt.Fprintf(writer, "Notifications:\t%v\n", len(user.Notifs))
+	fmt.Fprintf(writer, "Created At:\t%v\n", user.CreatedAt.Local().Format(userTimeFormat))
+	fmt.Fprintf(writer, "Marked For Deletion:\t%v\n", user.MarkedForDeletion)
+	writer.Flush()
+}
+
+var errUserChanged = errors.New("the user changed while updating them, nothing was changed. Try again")
+
+// Filter that only matches the user while they're in the state check approved of
+func checkedUserFilter(user User, checked bson.D) bson.D {
+	return append(bson.D{{Key: "id", Value: user.Id}}, checked...)
+}
+
+// Updates the user found by query, after check approves of them.
+// checked is the state check approved of, the update is skipped if the user changed since
+func updateUser(cmd *cobra.Command, query string, check func(user User) error, checked bson.D, update bson.D, done string) error {
+	client, coll, err := usersCollection()
+	if err != nil {
+		return err
+	}
+	defer disconnectDB(client)
+	user, err := findUser(context.TODO(), coll, query)
+	if err != nil {
+		return err
+	}
+	if err = check(user); err != nil {
+		return err
+	}
+	if dry {
+		cmd.Println(user.Username, done, "\nNOTICE: Did NOT save, due to dry run")
+		return nil
+	}
+	result, err := coll.UpdateOne(context.TODO(), checkedUserFilter(user, checked), update)
+	if err != nil {
+		return utilities.WrapMongoError(err)
+	}
+	if result.MatchedCount == 0 {
+		return errUserChanged
+	}
+	cmd.Println(user.Username, done)
+	return nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var usersShowJSON, usersDeleteHard, usersDeleteYes bool
+
+var usersShowCmd = &cobra.Command{
+	Use:     "show <id|username|email>",
+	Short:   "Show a user",
+	Example: "  " + utilities.Constants.RootCmdName + " users show admin@folderr.net",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, coll, err := usersCollection()
+		if err != nil {
+			return err
+		}
+		defer disconnectDB(client)
+		user, err := findUser(context.TODO(), coll, args[0])
+		if err != nil {
+			return err
+		}
+		if usersShowJSON {
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(user.toJSON())
+		}
+		printUser(cmd.OutOrStdout(), user)
+		return nil
+	},
+}
+
+// The state each check approves of, for checkedUserFilter
+var (
+	promoteChecked       = bson.D{{Key: "admin", Value: false}, {Key: "markedForDeletion", Value: false}}
+	demoteChecked        = bson.D{{Key: "owner", Value: false}, {Key: "admin", Value: true}}
+	markForDeleteChecked = bson.D{{Key: "owner", Value: false}, {Key: "markedForDeletion", Value: false}}
+	deleteChecked        = bson.D{{Key: "owner", Value: false}}
+)
+
+func checkPromote(user User) error {
+	if user.Admin {
+		return fmt.Errorf("%v is already an admin", user.Username)
+	}
+	if user.MarkedForDeletion {
+		return fmt.Errorf("%v is marked for deletion", user.Username)
+	}
+	return nil
+}
+
+func checkDemote(user User) error {
+	if user.Owner {
+		return fmt.Errorf("%v owns this instance. Transfer ownership before demoting them", user.Username)
+	}
+	if !user.Admin {
+		return fmt.Errorf("%v is not an admin", user.Username)
+	}
+	return nil
+}
+
+func checkDelete(user User) error {
+	if user.Owner {
+		return fmt.Errorf("%v owns this instance. Transfer ownership before deleting them", user.Username)
+	}
+	return nil
+}
+
+func checkMarkForDelete(user User) error {
+	if user.MarkedForDeletion {
+		return fmt.Errorf("%v is already marked for deletion", user.Username)
+	}
+	return checkDelete(user)
+}
+
+var usersPromoteCmd = &cobra.Command{
+	Use:   "promote <id|username|email>",
+	Short: "Make a user an admin",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateUser(cmd, args[0], checkPromote, promoteChecked,
+			bson.D{{Key: "$set", Value: bson.D{{Key: "admin", Value: true}}}}, "is now an admin")
+	},
+}
+
+var usersDemoteCmd = &cobra.Command{
+	Use:   "demote <id|username|email>",
+	Short: "Take admin away from a user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateUser(cmd, args[0], checkDemote, demoteChecked,
+			bson.D{{Key: "$set", Value: bson.D{{Key: "admin", Value: false}}}}, "is no longer an admin")
+	},
+}
+
+var usersDeleteCmd = &cobra.Command{
+	Use:   "delete <id|username|email>",
+	Short: "Delete a user",
+	Long: `Marks a user for deletion, so Folderr deletes them and their files.
+With --hard the user's document is deleted right away instead. Their files & links are left behind`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !usersDeleteHard {
+			return updateUser(cmd, args[0], checkMarkForDelete, markForDeleteChecked,
+				bson.D{{Key: "$set", Value: bson.D{{Key: "markedForDeletion", Value: true}}}}, "is marked for deletion")
+		}
+
+		client, coll, err := usersCollection()
+		if err != nil {
+			return err
+		}
+		defer disconnectDB(client)
+		user, err := findUser(context.TODO(), coll, args[0])
+		if err != nil {
+			return err
+		}
+		if err = checkDelete(user); err != nil {
+			return err
+		}
+		if dry {
+			cmd.Println("Deleted", user.Username, "\nNOTICE: Did NOT delete, due to dry run")
+			return nil
+		}
+		if !usersDeleteYes {
+			prompt := promptui.Prompt{
+				Label:     fmt.Sprintf("Delete %v (%v) forever", user.Username, user.Email),
+				IsConfirm: true,
+			}
+			if _, err = prompt.Run(); err != nil {
+				return errors.New("cancelled")
+			}
+		}
+		result, err := coll.DeleteOne(context.TODO(), checkedUserFilter(user, deleteChecked))
+		if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		if result.DeletedCount == 0 {
+			return errUserChanged
+		}
+		cmd.Println("Deleted", user.Username)
+		return nil
+	},
+}
+
+func init() {
+	usersShowCmd.Flags().BoolVar(&usersShowJSON, "json", false, "Output JSON")
+	usersDeleteCmd.Flags().BoolVar(&usersDeleteHard, "hard", false, "Delete the user's document now instead of marking them for deletion")
+	usersDeleteCmd.Flags().BoolVarP(&usersDeleteYes, "yes", "y", false, "Don't ask before deleting with --hard")
+	usersCmd.AddCommand(usersShowCmd, usersPromoteCmd, usersDemoteCmd, usersDeleteCmd)
+}
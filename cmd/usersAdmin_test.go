@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestUserChecks(t *testing.T) {
+	user := User{Username: "alice"}
+	admin := User{Username: "bob", Admin: true}
+	owner := User{Username: "carol", Admin: true, Owner: true}
+	marked := User{Username: "dave", MarkedForDeletion: true}
+
+	cases := []struct {
+		name  string
+		check func(User) error
+		user  User
+		ok    bool
+	}{
+		{"promote user", checkPromote, user, true},
+		{"promote admin", checkPromote, admin, false},
+		{"promote marked", checkPromote, marked, false},
+		{"demote admin", checkDemote, admin, true},
+		{"demote user", checkDemote, user, false},
+		{"demote owner", checkDemote, owner, false},
+		{"mark user", checkMarkForDelete, user, true},
+		{"mark marked", checkMarkForDelete, marked, false},
+		{"mark owner", checkMarkForDelete, owner, false},
+		{"delete marked", checkDelete, marked, true},
+		{"delete owner", checkDelete, owner, false},
+	}
+	for _, c := range cases {
+		if err := c.check(c.user); (err == nil) != c.ok {
+			t.Errorf("%v: expected ok to be %v, got %v", c.name, c.ok, err)
+		}
+	}
+}
+
+func TestCheckedUserFilter(t *testing.T) {
+	user := User{Id: "1234"}
+	filter := checkedUserFilter(user, demoteChecked)
+	expected := bson.D{{Key: "id", Value: "1234"}, {Key: "owner", Value: false}, {Key: "admin", Value: true}}
+	if len(filter) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, filter)
+	}
+	for i := range expected {
+		if filter[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, filter)
+		}
+	}
+	// The checked state is shared, building a filter mustn't change it
+	if len(demoteChecked) != 2 {
+		t.Errorf("expected demoteChecked to be left alone, got %v", demoteChecked)
+	}
+}
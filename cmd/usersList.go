@@ -0,0 +1,109 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var usersListAdmin, usersListOwner, usersListMarked, usersListJSON bool
+var usersListLimit, usersListPage int64
+
+var usersListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List users",
+	Long: `List users, oldest first.
+Filter with --admin, --owner and --marked-for-deletion, i.e --admin=false lists users that aren't admins`,
+	Example: "  " + utilities.Constants.RootCmdName + " users list --admin\n  " +
+		utilities.Constants.RootCmdName + " users list --marked-for-deletion --json\n  " +
+		utilities.Constants.RootCmdName + " users list --limit 20 --page 2",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if usersListLimit < 1 || usersListPage < 1 {
+			return errors.New("--limit and --page must be at least 1")
+		}
+		filter := bson.D{}
+		flags := cmd.Flags()
+		if flags.Changed("admin") {
+			filter = append(filter, bson.E{Key: "admin", Value: usersListAdmin})
+		}
+		if flags.Changed("owner") {
+			filter = append(filter, bson.E{Key: "owner", Value: usersListOwner})
+		}
+		if flags.Changed("marked-for-deletion") {
+			filter = append(filter, bson.E{Key: "markedForDeletion", Value: usersListMarked})
+		}
+
+		client, coll, err := usersCollection()
+		if err != nil {
+			return err
+		}
+		defer disconnectDB(client)
+
+		total, err := coll.CountDocuments(context.TODO(), filter)
+		if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		cursor, err := coll.Find(context.TODO(), filter, options.Find().
+			SetSort(bson.D{{Key: "createdAt", Value: 1}}).
+			SetSkip((usersListPage-1)*usersListLimit).
+			SetLimit(usersListLimit))
+		if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		users := []User{}
+		if err = cursor.All(context.TODO(), &users); err != nil {
+			return utilities.WrapMongoError(err)
+		}
+
+		if usersListJSON {
+			output := []userJSON{}
+			for _, user := range users {
+				output = append(output, user.toJSON())
+			}
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(output)
+		}
+
+		writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, "ID\tUSERNAME\tEMAIL\tADMIN\tOWNER\tMARKED FOR DELETION\tCREATED")
+		for _, user := range users {
+			fmt.Fprintf(
+				writer,
+				"%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+				user.Id,
+				user.Username,
+				user.Email,
+				user.Admin,
+				user.Owner,
+				user.MarkedForDeletion,
+				user.CreatedAt.Local().Format("2006-01-02 15:04"),
+			)
+		}
+		writer.Flush()
+		pages := (total + usersListLimit - 1) / usersListLimit
+		cmd.Printf("\nPage %v of %v, %v users\n", usersListPage, pages, total)
+		return nil
+	},
+}
+
+func init() {
+	usersListCmd.Flags().BoolVar(&usersListAdmin, "admin", false, "Only list admins, or with =false only users who aren't")
+	usersListCmd.Flags().BoolVar(&usersListOwner, "owner", false, "Only list the owner, or with =false everyone else")
+	usersListCmd.Flags().BoolVar(&usersListMarked, "marked-for-deletion", false, "Only list users marked for deletion, or with =false users who aren't")
+	usersListCmd.Flags().Int64Var(&usersListLimit, "limit", 50, "Users per page")
+	usersListCmd.Flags().Int64Var(&usersListPage, "page", 1, "Page to show")
+	usersListCmd.Flags().BoolVar(&usersListJSON, "json", false, "Output JSON")
+	usersCmd.AddCommand(usersListCmd)
+}
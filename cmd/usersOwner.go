@@ -0,0 +1,154 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var errOwnerChanged = errors.New("the users changed while transferring ownership, nothing was changed. Try again")
+
+// Filters that only match the users while they're in the state we checked
+func transferFilters(from User, to User) (bson.D, bson.D) {
+	fromFilter := bson.D{{Key: "id", Value: from.Id}, {Key: "owner", Value: true}}
+	toFilter := bson.D{{Key: "id", Value: to.Id}, {Key: "owner", Value: false}, {Key: "markedForDeletion", Value: false}}
+	return fromFilter, toFilter
+}
+
+func setOwner(owner bool, admin bool) bson.D {
+	return bson.D{{Key: "$set", Value: bson.D{{Key: "owner", Value: owner}, {Key: "admin", Value: admin}}}}
+}
+
+// Moves ownership in one transaction
+func transferOwnerTransaction(ctx context.Context, client *mongo.Client, coll *mongo.Collection, from User, to User) error {
+	session, err := client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+	fromFilter, toFilter := transferFilters(from, to)
+	_, err = session.WithTransaction(ctx, func(ctx mongo.SessionContext) (interface{}, error) {
+		result, err := coll.UpdateOne(ctx, fromFilter, bson.D{{Key: "$set", Value: bson.D{{Key: "owner", Value: false}}}})
+		if err != nil {
+			return nil, err
+		}
+		if result.MatchedCount == 0 {
+			return nil, errOwnerChanged
+		}
+		result, err = coll.UpdateOne(ctx, toFilter, setOwner(true, true))
+		if err != nil {
+			return nil, err
+		}
+		if result.MatchedCount == 0 {
+			return nil, errOwnerChanged
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// Moves ownership without a transaction, for standalone servers.
+// The new owner is set first so the instance is never without an owner, and is reverted if the old owner changed
+func transferOwnerCompareAndSwap(ctx context.Context, coll *mongo.Collection, from User, to User) error {
+	fromFilter, toFilter := transferFilters(from, to)
+	result, err := coll.UpdateOne(ctx, toFilter, setOwner(true, true))
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errOwnerChanged
+	}
+	result, err = coll.UpdateOne(ctx, fromFilter, bson.D{{Key: "$set", Value: bson.D{{Key: "owner", Value: false}}}})
+	if err == nil && result.MatchedCount == 1 {
+		return nil
+	}
+	_, revertErr := coll.UpdateOne(ctx, bson.D{{Key: "id", Value: to.Id}}, setOwner(false, to.Admin))
+	if revertErr != nil {
+		return fmt.Errorf(
+			"failed to transfer ownership, and failed to undo making %v an owner. Run \"%v users list --owner\" and fix it by hand\n%w",
+			to.Username,
+			rootCmdName,
+			utilities.WrapMongoError(revertErr),
+		)
+	}
+	if err != nil {
+		return err
+	}
+	return errOwnerChanged
+}
+
+var usersTransferOwnerCmd = &cobra.Command{
+	Use:   "transfer-owner <from> <to>",
+	Short: "Make another user the owner of this instance",
+	Long: `Make another user the owner (and an admin) of this instance. The old owner stays an admin.
+Users are found by their ID, username or email.
+Uses a transaction when the database is a replica set or sharded cluster`,
+	Example: "  " + utilities.Constants.RootCmdName + " users transfer-owner alice bob",
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, coll, err := usersCollection()
+		if err != nil {
+			return err
+		}
+		defer disconnectDB(client)
+		ctx := context.TODO()
+
+		from, err := findUser(ctx, coll, args[0])
+		if err != nil {
+			return err
+		}
+		to, err := findUser(ctx, coll, args[1])
+		if err != nil {
+			return err
+		}
+		owners, err := coll.CountDocuments(ctx, bson.D{{Key: "owner", Value: true}})
+		if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		if owners != 1 {
+			return fmt.Errorf("expected exactly one owner, found %v. Run \"%v users list --owner\" and fix it by hand", owners, rootCmdName)
+		}
+		if !from.Owner {
+			return fmt.Errorf("%v is not the owner", from.Username)
+		}
+		if from.Id == to.Id {
+			return fmt.Errorf("%v already owns this instance", to.Username)
+		}
+		if to.MarkedForDeletion {
+			return fmt.Errorf("%v is marked for deletion, they can't own this instance", to.Username)
+		}
+
+		transactions, err := supportsTransactions(ctx, client)
+		if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		if dry {
+			cmd.Println("Transferred ownership from", from.Username, "to", to.Username, "\nNOTICE: Did NOT save, due to dry run")
+			return nil
+		}
+		if transactions {
+			err = transferOwnerTransaction(ctx, client, coll, from, to)
+		} else {
+			err = transferOwnerCompareAndSwap(ctx, coll, from, to)
+		}
+		if errors.Is(err, errOwnerChanged) || utilities.ClassifyMongoError(err) == utilities.MongoUnknown {
+			return err
+		} else if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		cmd.Println("Transferred ownership from", from.Username, "to", to.Username)
+		return nil
+	},
+}
+
+func init() {
+	usersCmd.AddCommand(usersTransferOwnerCmd)
+}
@@ -0,0 +1,206 @@
+/*
+Copyright © 2023 Folderr <contact@folderr.net>
+*/
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/Folderr/foldcli/utilities"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var resetPasswordStdin, resetPasswordGenerate bool
+
+const (
+	generatedPasswordLength = 24
+	generatedPasswordChars  = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789!#%&*+-=?@^_"
+	generatePasswordTries   = 100
+)
+
+// Generates a random password that follows the policy
+func generatePassword(policy utilities.PasswordPolicy) (string, error) {
+	length := generatedPasswordLength
+	if policy.MinLength > length {
+		length = policy.MinLength
+	}
+	if policy.MaxLength > 0 && length > policy.MaxLength {
+		length = policy.MaxLength
+	}
+	if length < len(policy.Classes) {
+		return "", fmt.Errorf("can't generate a password, it needs %v kinds of characters but can only be %v long", len(policy.Classes), length)
+	}
+
+	// Only the banned list can reject these, so a few tries is plenty
+	for try := 0; try < generatePasswordTries; try++ {
+		password := make([]byte, 0, length)
+		// One of each class the policy needs, then anything
+		for _, class := range policy.Classes {
+			char, err := randomPasswordChar(class.Matches)
+			if err != nil {
+				return "", err
+			}
+			password = append(password, char)
+		}
+		for len(password) < length {
+			char, err := randomPasswordChar(nil)
+			if err != nil {
+				return "", err
+			}
+			password = append(password, char)
+		}
+		// So the required characters aren't always first
+		for i := len(password) - 1; i > 0; i-- {
+			j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+			if err != nil {
+				return "", err
+			}
+			password[i], password[j.Int64()] = password[j.Int64()], password[i]
+		}
+		if policy.Check(string(password)) == nil {
+			return string(password), nil
+		}
+	}
+	return "", fmt.Errorf("failed to generate a password that follows the policy after %v tries", generatePasswordTries)
+}
+
+// Picks a random character for generated passwords, from those matching filter (if any)
+func randomPasswordChar(filter func(rune) bool) (byte, error) {
+	chars := generatedPasswordChars
+	if filter != nil {
+		chars = strings.Map(func(r rune) rune {
+			if filter(r) {
+				return r
+			}
+			return -1
+		}, chars)
+	}
+	if chars == "" {
+		return 0, fmt.Errorf("no characters to generate a password from")
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+	if err != nil {
+		return 0, err
+	}
+	return chars[n.Int64()], nil
+}
+
+// Reads a password from the first line of r
+func readPassword(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("no password given on stdin")
+	}
+	return password, nil
+}
+
+// Asks for a password twice without showing it
+func promptPassword(cmd *cobra.Command, label string, policy utilities.PasswordPolicy) (string, error) {
+	cmd.Println(policy.Requirements())
+	prompt := promptui.Prompt{Label: label, Mask: '*', Validate: policy.Check}
+	password, err := prompt.Run()
+	if err != nil {
+		return "", err
+	}
+	confirm := promptui.Prompt{
+		Label: "Confirm password",
+		Mask:  '*',
+		Validate: func(input string) error {
+			if input != password {
+				return errors.New("passwords do not match")
+			}
+			return nil
+		},
+	}
+	_, err = confirm.Run()
+	return password, err
+}
+
+var usersResetPasswordCmd = &cobra.Command{
+	Use:   "reset-password <id|username|email>",
+	Short: "Set a new password for a user",
+	Long: `Set a new password for a user, i.e an owner who is locked out.
+The password is asked for without showing it, read from stdin with --password-stdin,
+or generated with --generate. Generated passwords are only shown once`,
+	Example: "  " + utilities.Constants.RootCmdName + " users reset-password admin\n  " +
+		"cat password.txt | " + utilities.Constants.RootCmdName + " users reset-password admin --password-stdin\n  " +
+		utilities.Constants.RootCmdName + " users reset-password admin --generate",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if resetPasswordStdin && resetPasswordGenerate {
+			return errors.New("use either --password-stdin or --generate, not both")
+		}
+		config, err := readDBConfig()
+		if err != nil {
+			return err
+		}
+		policy, err := utilities.LoadPolicy(config.Policy)
+		if err != nil {
+			return err
+		}
+		client, coll, err := usersCollection()
+		if err != nil {
+			return err
+		}
+		defer disconnectDB(client)
+		user, err := findUser(context.TODO(), coll, args[0])
+		if err != nil {
+			return err
+		}
+
+		var password string
+		switch {
+		case resetPasswordGenerate:
+			password, err = generatePassword(policy.Password)
+		case resetPasswordStdin:
+			password, err = readPassword(cmd.InOrStdin())
+			if err == nil {
+				err = policy.Password.Check(password)
+			}
+		default:
+			password, err = promptPassword(cmd, "New password for "+user.Username, policy.Password)
+		}
+		if err != nil {
+			return err
+		}
+
+		hashed, err := hashPassword(password)
+		if err != nil {
+			return fmt.Errorf("failed to hash the password: %w", err)
+		}
+		if dry {
+			cmd.Println("Reset the password of", user.Username, "\nNOTICE: Did NOT save, due to dry run")
+			return nil
+		}
+		_, err = coll.UpdateOne(context.TODO(), bson.D{{Key: "id", Value: user.Id}}, bson.D{
+			{Key: "$set", Value: bson.D{{Key: "password", Value: hashed}}},
+		})
+		if err != nil {
+			return utilities.WrapMongoError(err)
+		}
+		cmd.Println("Reset the password of", user.Username)
+		if resetPasswordGenerate {
+			cmd.Println("New password (shown once, keep it safe):", password)
+		}
+		return nil
+	},
+}
+
+func init() {
+	usersResetPasswordCmd.Flags().BoolVar(&resetPasswordStdin, "password-stdin", false, "Read the new password from stdin")
+	usersResetPasswordCmd.Flags().BoolVar(&resetPasswordGenerate, "generate", false, "Generate a random password")
+	usersCmd.AddCommand(usersResetPasswordCmd)
+}
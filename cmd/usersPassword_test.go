@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/Folderr/foldcli/utilities"
+)
+
+func TestGeneratePassword(t *testing.T) {
+	policy := utilities.DefaultPolicy().Password
+	seen := map[string]bool{}
+	for i := 0; i < 5; i++ {
+		password, err := generatePassword(policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = policy.Check(password); err != nil {
+			t.Errorf("Generated password %q is invalid: %v", password, err)
+		}
+		if seen[password] {
+			t.Errorf("Generated password %q twice", password)
+		}
+		seen[password] = true
+	}
+}
+
+// Used to loop forever when the max length was under the generated length
+func TestGeneratePasswordShortMaxLength(t *testing.T) {
+	classes, err := utilities.ParseCharClasses("lower,upper,digit,symbol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := utilities.PasswordPolicy{MinLength: 4, MaxLength: 8, Classes: classes}
+	for i := 0; i < 20; i++ {
+		password, err := generatePassword(policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(password) != 8 {
+			t.Errorf("Expected 8 characters, got %q", password)
+		}
+		if err = policy.Check(password); err != nil {
+			t.Errorf("Generated password %q is invalid: %v", password, err)
+		}
+	}
+
+	policy.MaxLength = 3
+	if _, err = generatePassword(policy); err == nil {
+		t.Error("Expected an error when the classes don't fit in the max length")
+	}
+}
@@ -11,6 +11,7 @@ require (
 	github.com/spf13/viper v1.17.0
 	go.mongodb.org/mongo-driver v1.13.1
 	golang.org/x/crypto v0.15.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -65,5 +66,4 @@ require (
 	golang.org/x/tools v0.15.0 // indirect
 	gopkg.in/ini.v1 v1.67.0 // indirect
 	gopkg.in/warnings.v0 v0.1.2 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
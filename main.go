@@ -6,8 +6,10 @@ package main
 
 import (
 	"github.com/Folderr/foldcli/cmd"
+	_ "github.com/Folderr/foldcli/cmd/config"
 	_ "github.com/Folderr/foldcli/cmd/init"
 	_ "github.com/Folderr/foldcli/cmd/install"
+	_ "github.com/Folderr/foldcli/cmd/secrets"
 )
 
 func main() {
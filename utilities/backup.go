@@ -0,0 +1,258 @@
+package utilities
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Backups are zip archives holding one file of Extended JSON documents (one per line) per collection,
+// and a manifest
+const (
+	backupManifestName  = "manifest.json"
+	backupCollectionExt = ".jsonl"
+	// Documents inserted at once when restoring
+	restoreBatchSize = 500
+)
+
+type BackupManifest struct {
+	FoldcliVersion string           `json:"foldcliVersion"`
+	SchemaVersion  int              `json:"schemaVersion"`
+	Database       string           `json:"database"`
+	CreatedAt      time.Time        `json:"createdAt"`
+	Collections    map[string]int64 `json:"collections"`
+}
+
+// The collections in the manifest, sorted by name
+func (m BackupManifest) CollectionNames() []string {
+	names := []string{}
+	for name := range m.Collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Streams every collection of db into w as a backup archive
+func BackupDatabase(ctx context.Context, db *mongo.Database, w io.Writer, foldcliVersion string) (BackupManifest, error) {
+	manifest := BackupManifest{
+		FoldcliVersion: foldcliVersion,
+		Database:       db.Name(),
+		CreatedAt:      time.Now().UTC(),
+		Collections:    map[string]int64{},
+	}
+	schemaVersion, err := SchemaVersion(ctx, db)
+	if err != nil {
+		return manifest, err
+	}
+	manifest.SchemaVersion = schemaVersion
+	names, err := db.ListCollectionNames(ctx, bson.D{})
+	if err != nil {
+		return manifest, err
+	}
+	sort.Strings(names)
+
+	archive := zip.NewWriter(w)
+	for _, name := range names {
+		if strings.HasPrefix(name, "system.") {
+			continue
+		}
+		file, err := archive.Create(name + backupCollectionExt)
+		if err != nil {
+			return manifest, err
+		}
+		filter := bson.D{}
+		if name == MigrationsCollection {
+			// A restored lock would block migrations until it expires
+			filter = bson.D{{Key: "_id", Value: bson.D{{Key: "$ne", Value: migrationLockId}}}}
+		}
+		count, err := backupCollection(ctx, db.Collection(name), filter, file)
+		if err != nil {
+			return manifest, fmt.Errorf("failed to back up %v: %w", name, err)
+		}
+		manifest.Collections[name] = count
+	}
+
+	file, err := archive.Create(backupManifestName)
+	if err != nil {
+		return manifest, err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(manifest); err != nil {
+		return manifest, err
+	}
+	return manifest, archive.Close()
+}
+
+func backupCollection(ctx context.Context, coll *mongo.Collection, filter bson.D, w io.Writer) (int64, error) {
+	cursor, err := coll.Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+	var count int64
+	for cursor.Next(ctx) {
+		line, err := bson.MarshalExtJSON(cursor.Current, true, false)
+		if err != nil {
+			return count, err
+		}
+		if _, err = w.Write(append(line, '\n')); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, cursor.Err()
+}
+
+// Reads the manifest of a backup archive
+func ReadBackupManifest(archive *zip.Reader) (BackupManifest, error) {
+	manifest := BackupManifest{}
+	file, err := archive.Open(backupManifestName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return manifest, errors.New("the backup has no manifest, it may be incomplete")
+	} else if err != nil {
+		return manifest, err
+	}
+	defer file.Close()
+	err = json.NewDecoder(file).Decode(&manifest)
+	if err != nil {
+		return manifest, fmt.Errorf("corrupt backup manifest: %w", err)
+	}
+	for _, name := range manifest.CollectionNames() {
+		if _, err := fs.Stat(archive, name+backupCollectionExt); err != nil {
+			return manifest, fmt.Errorf("collection %v is in the manifest, but not the backup", name)
+		}
+	}
+	return manifest, nil
+}
+
+// Loads the collections of a backup archive into db.
+//
+// Without drop the collections must be empty. With drop each collection is restored into a temporary collection,
+// which is then renamed over the original. A failure leaves the collection it happened on as it was
+func RestoreDatabase(ctx context.Context, db *mongo.Database, archive *zip.Reader, manifest BackupManifest, drop bool) error {
+	if drop {
+		replaced := []string{}
+		for _, name := range manifest.CollectionNames() {
+			if err := replaceCollection(ctx, db, archive, name); err != nil {
+				if len(replaced) > 0 {
+					return fmt.Errorf("%w. Already replaced: %v", err, strings.Join(replaced, ", "))
+				}
+				return err
+			}
+			replaced = append(replaced, name)
+		}
+		return nil
+	}
+
+	for _, name := range manifest.CollectionNames() {
+		count, err := db.Collection(name).CountDocuments(ctx, bson.D{})
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("collection %v of %v has %v documents, restore with --drop to replace them", name, db.Name(), count)
+		}
+	}
+	for _, name := range manifest.CollectionNames() {
+		if err := restoreCollectionFile(ctx, db.Collection(name), archive, name); err != nil {
+			return fmt.Errorf("failed to restore %v: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Name of the collection name is restored into before it replaces the original
+func restoreTempName(name string) string {
+	return "foldcli_restore_" + name
+}
+
+// Restores name into a temporary collection, then renames it over the original
+func replaceCollection(ctx context.Context, db *mongo.Database, archive *zip.Reader, name string) error {
+	temp := db.Collection(restoreTempName(name))
+	// Left over from a restore that died
+	if err := temp.Drop(ctx); err != nil {
+		return fmt.Errorf("failed to restore %v: %w", name, err)
+	}
+	// Nothing is inserted for empty collections, so it isn't created by restoring
+	if err := db.CreateCollection(ctx, temp.Name()); err != nil {
+		return fmt.Errorf("failed to restore %v: %w", name, err)
+	}
+	if err := restoreCollectionFile(ctx, temp, archive, name); err != nil {
+		temp.Drop(ctx)
+		return fmt.Errorf("failed to restore %v, it was left as it was: %w", name, err)
+	}
+	err := db.Client().Database("admin").RunCommand(ctx, bson.D{
+		{Key: "renameCollection", Value: db.Name() + "." + temp.Name()},
+		{Key: "to", Value: db.Name() + "." + name},
+		{Key: "dropTarget", Value: true},
+	}).Err()
+	if err != nil {
+		temp.Drop(ctx)
+		return fmt.Errorf("failed to replace %v, it was left as it was: %w", name, err)
+	}
+	return nil
+}
+
+func restoreCollectionFile(ctx context.Context, coll *mongo.Collection, archive *zip.Reader, name string) error {
+	file, err := archive.Open(name + backupCollectionExt)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return restoreCollection(ctx, coll, file)
+}
+
+func restoreCollection(ctx context.Context, coll *mongo.Collection, contents io.Reader) error {
+	reader := bufio.NewReader(contents)
+	batch := []interface{}{}
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			var doc bson.D
+			if err := bson.UnmarshalExtJSON(line, true, &doc); err != nil {
+				return err
+			}
+			batch = append(batch, doc)
+		}
+		if len(batch) >= restoreBatchSize || (errors.Is(err, io.EOF) && len(batch) > 0) {
+			if _, insertErr := coll.InsertMany(ctx, batch); insertErr != nil {
+				return insertErr
+			}
+			batch = []interface{}{}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Compares the document counts of db against the manifest. Returns the collections that don't match
+func VerifyRestore(ctx context.Context, db *mongo.Database, manifest BackupManifest) ([]string, error) {
+	mismatched := []string{}
+	for _, name := range manifest.CollectionNames() {
+		count, err := db.Collection(name).CountDocuments(ctx, bson.D{})
+		if err != nil {
+			return nil, err
+		}
+		if count != manifest.Collections[name] {
+			mismatched = append(mismatched, fmt.Sprintf("%v: expected %v documents, found %v", name, manifest.Collections[name], count))
+		}
+	}
+	return mismatched, nil
+}
@@ -0,0 +1,64 @@
+package utilities
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Needs a database, set FOLDCLI_MONGO_URI to run it
+func TestBackupRestoreDrop(t *testing.T) {
+	uri := os.Getenv(Constants.EnvPrefix + "MONGO_URI")
+	if uri == "" {
+		t.Skip("No MONGO_URI environment variable provided. No DB operations available")
+	}
+	client, err := NewMongoClient(DBConfig{Url: uri}, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	defer client.Disconnect(ctx)
+	db := client.Database("foldcli-backup-testing")
+	defer db.Drop(ctx)
+
+	if _, err = db.Collection("users").InsertOne(ctx, bson.D{{Key: "id", Value: "1"}}); err != nil {
+		t.Fatal(err)
+	}
+	release, err := AcquireMigrationLock(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backup := &bytes.Buffer{}
+	manifest, err := BackupDatabase(ctx, db, backup, "test")
+	release()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Collections[MigrationsCollection] != 0 {
+		t.Errorf("Expected the migration lock to be left out of the backup, got %v documents", manifest.Collections[MigrationsCollection])
+	}
+
+	if _, err = db.Collection("users").InsertOne(ctx, bson.D{{Key: "id", Value: "2"}}); err != nil {
+		t.Fatal(err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(backup.Bytes()), int64(backup.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = RestoreDatabase(ctx, db, archive, manifest, true); err != nil {
+		t.Fatal(err)
+	}
+	mismatched, err := VerifyRestore(ctx, db, manifest)
+	if err != nil || len(mismatched) > 0 {
+		t.Errorf("Expected the restore to match the backup, got %v (%v)", mismatched, err)
+	}
+	names, err := db.ListCollectionNames(ctx, bson.D{{Key: "name", Value: restoreTempName("users")}})
+	if err != nil || len(names) > 0 {
+		t.Errorf("Expected the temporary collections to be gone, got %v (%v)", names, err)
+	}
+}
@@ -0,0 +1,84 @@
+package utilities
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/mail"
+	"strings"
+)
+
+// Normalises a ban list entry: an email, IP address or CIDR range.
+// Emails are lowercased, IPs and ranges are written the way Go (and node) print them
+func NormalizeBan(entry string) (string, error) {
+	entry = strings.TrimSpace(entry)
+	if entry == "" {
+		return "", errors.New("empty entry")
+	}
+	if strings.Contains(entry, "/") {
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a CIDR range", entry)
+		}
+		return network.String(), nil
+	}
+	if ip := net.ParseIP(entry); ip != nil {
+		return ip.String(), nil
+	}
+	if strings.Contains(entry, "@") {
+		address, err := mail.ParseAddress(entry)
+		if err != nil || address.Address != entry {
+			return "", fmt.Errorf("%q is not an email", entry)
+		}
+		return strings.ToLower(address.Address), nil
+	}
+	return "", fmt.Errorf("%q is not an email, IP address or CIDR range", entry)
+}
+
+// Reads ban list entries from a file with one entry per line, or a CSV file with the entries in the first column.
+// Blank lines, lines starting with # and a header row are skipped.
+// Returns the valid entries (without duplicates) and one error per invalid entry
+func ParseBanList(r io.Reader) ([]string, []error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+	entries := []string{}
+	errs := []error{}
+	seen := map[string]bool{}
+	first := true
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		header := first
+		first = false
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		field := strings.TrimSpace(record[0])
+		if field == "" {
+			continue
+		}
+		ban, err := NormalizeBan(field)
+		if err != nil {
+			if header && !strings.ContainsAny(field, "@.:/") {
+				// Header, i.e "entry,reason"
+				continue
+			}
+			// The line in the file, blank & comment lines included
+			line, _ := reader.FieldPos(0)
+			errs = append(errs, fmt.Errorf("line %v: %w", line, err))
+			continue
+		}
+		if !seen[ban] {
+			seen[ban] = true
+			entries = append(entries, ban)
+		}
+	}
+	return entries, errs
+}
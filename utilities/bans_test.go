@@ -0,0 +1,41 @@
+package utilities
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeBan(t *testing.T) {
+	valid := map[string]string{
+		"Spammer@Example.com": "spammer@example.com",
+		" 192.0.2.1 ":         "192.0.2.1",
+		"2001:DB8::1":         "2001:db8::1",
+		"192.0.2.77/24":       "192.0.2.0/24",
+	}
+	for entry, expected := range valid {
+		ban, err := NormalizeBan(entry)
+		if err != nil || ban != expected {
+			t.Errorf("Expected %q to become %q, got %q (%v)", entry, expected, ban, err)
+		}
+	}
+	for _, entry := range []string{"", "spammer", "192.0.2.300", "192.0.2.0/33", "Spammer <spammer@example.com>"} {
+		if _, err := NormalizeBan(entry); err == nil {
+			t.Errorf("Expected %q to be invalid", entry)
+		}
+	}
+}
+
+func TestParseBanList(t *testing.T) {
+	input := "entry,reason\n# spammers\nspam@example.com,spam\n\n192.0.2.1\n10.0.0.0/8\nSPAM@example.com,duplicate\nnot-an-entry\n"
+	entries, errs := ParseBanList(strings.NewReader(input))
+	if len(entries) != 3 {
+		t.Errorf("Expected 3 unique entries, got %v", entries)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 invalid entry, got %v", errs)
+	}
+	// Counted from the top of the file, not from the entries
+	if !strings.HasPrefix(errs[0].Error(), "line 8:") {
+		t.Errorf("Expected the error to be on line 8, got %v", errs[0])
+	}
+}
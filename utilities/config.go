@@ -1,66 +1,221 @@
 package utilities
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Directory  string   `json:"directory"`
-	Repository string   `json:"repository"`
-	CanInstall bool     `json:"CanInstall"`
-	Database   DBConfig `json:"db" mapstructure:"db"`
+	ConfigVersion int          `json:"configVersion" mapstructure:"configVersion"`
+	Directory     string       `json:"directory"`
+	Repository    string       `json:"repository"`
+	ReleaseType   string       `json:"releaseType" mapstructure:"releaseType"`
+	Release       string       `json:"release"`
+	Database      DBConfig     `json:"db" mapstructure:"db"`
+	Policy        PolicyConfig `json:"policy"`
+	// Computed from Directory & Repository, never persisted
+	CanInstall bool `json:"CanInstall" mapstructure:"-"`
 }
 
 type DBConfig struct {
 	DbName string `json:"dbName"`
 	Url    string `json:"url"`
+	// Connection options on top of the URI, see MongoClientOptions
+	TLSCAFile              string `json:"tlsCAFile" mapstructure:"tlsCAFile"`
+	TLSCertFile            string `json:"tlsCertFile" mapstructure:"tlsCertFile"`
+	TLSKeyFile             string `json:"tlsKeyFile" mapstructure:"tlsKeyFile"`
+	AuthSource             string `json:"authSource" mapstructure:"authSource"`
+	AuthMechanism          string `json:"authMechanism" mapstructure:"authMechanism"`
+	ReplicaSet             string `json:"replicaSet" mapstructure:"replicaSet"`
+	ConnectTimeout         string `json:"connectTimeout" mapstructure:"connectTimeout"`
+	ServerSelectionTimeout string `json:"serverSelectionTimeout" mapstructure:"serverSelectionTimeout"`
+	AppName                string `json:"appName" mapstructure:"appName"`
 }
 
+// Returned by ReadConfig when the config file has unknown keys or invalid values
+type ConfigError struct {
+	Path string
+	Errs []error
+}
+
+func (e *ConfigError) Error() string {
+	lines := []string{fmt.Sprintf("config file %q is invalid:", e.Path)}
+	for _, err := range e.Errs {
+		lines = append(lines, "  - "+err.Error())
+	}
+	lines = append(lines, "Fix it with \""+Constants.RootCmdName+" config edit\"")
+	return strings.Join(lines, "\n")
+}
+
+// Secrets from the secrets file (see "foldcli secrets") and the environment
 type SecretConfig struct {
 	GitToken string
+	DBUrl    string
+	// The secrets file exists, but nothing unlocked it
+	Locked bool
 }
 
+// Errors when the credentials for the database may be in a secrets file nothing unlocked.
+// Commands that connect to the database check this, instead of connecting without credentials
+func RequireDBSecrets(config Config, secrets SecretConfig) error {
+	if !secrets.Locked {
+		return nil
+	}
+	if uri, err := LookupSecretEnv(Constants.EnvPrefix + "MONGO_URI"); err != nil || uri != "" {
+		return err
+	}
+	if uri, err := url.Parse(config.Database.Url); err == nil && uri.User != nil {
+		return nil
+	}
+	return fmt.Errorf("the database credentials may be in the secrets file, which can't be read: %w", ErrSecretsLocked)
+}
+
+// Returns the git token from the environment, or an empty string. Problems reading it are printed as warnings.
 func GetGitToken() string {
-	token := os.Getenv(strings.ToLower(Constants.EnvPrefix) + "git_token")
-	if token == "" {
-		token = os.Getenv(Constants.EnvPrefix + "GIT_TOKEN")
+	token, err := ResolveGitToken()
+	if err != nil {
+		println("Warning:", err.Error())
 	}
 	return token
 }
 
+// Reads the git token from the FOLDCLI_GIT_TOKEN env variable or the file FOLDCLI_GIT_TOKEN_FILE names
+func ResolveGitToken() (string, error) {
+	token, err := LookupSecretEnv(strings.ToLower(Constants.EnvPrefix) + "git_token")
+	if token != "" || err != nil {
+		return token, err
+	}
+	return LookupSecretEnv(Constants.EnvPrefix + "GIT_TOKEN")
+}
+
+// Config file or directory chosen with the global --config flag
+var configPathOverride string
+
+// Points the CLI at a config file or directory other than the default. Set by the global --config flag
+func SetConfigPath(path string) {
+	configPathOverride = path
+}
+
+// Returns the config path the user chose with --config or the FOLDCLI_CONFIG env variable, if any
+func userConfigPath() string {
+	if configPathOverride != "" {
+		return configPathOverride
+	}
+	return os.Getenv(Constants.EnvPrefix + "CONFIG")
+}
+
+// Whether path points to a config file rather than a directory
+func isConfigFilePath(path string) bool {
+	if info, err := os.Stat(path); err == nil {
+		return !info.IsDir()
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// Returns the path of the config file in the config directory dir.
+// This is "config.yaml" unless the user chose a file with --config or FOLDCLI_CONFIG
+func ConfigFilePath(dir string) string {
+	if path := userConfigPath(); path != "" {
+		// dir is absolute (see GetConfigDir), the path the user gave may not be
+		path, err := filepath.Abs(path)
+		if err == nil && isConfigFilePath(path) && filepath.Dir(path) == filepath.Clean(dir) {
+			return path
+		}
+	}
+	return filepath.Join(dir, "config.yaml")
+}
+
+// Returns the directory the CLI keeps its config (and keys) in.
+//
+// In order of priority: the --config flag, the FOLDCLI_CONFIG env variable,
+// temp directories for tests, $XDG_CONFIG_HOME/foldcli and lastly $HOME/.folderr/cli
 func GetConfigDir(dry bool) (string, error) {
-	dir, err := os.UserHomeDir()
 	if dry && os.Getenv(Constants.EnvPrefix+"DEBUG") == "true" {
 		fmt.Println("Using dry-run mode")
 	}
-	if err != nil && dry {
-		println("Error accessing user directory:", err)
-		println("This is a warning as you are in dry-run mode")
-	} else if err != nil {
-		return "", err
+	if path := userConfigPath(); path != "" {
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return "", err
+		}
+		if isConfigFilePath(path) {
+			return filepath.Dir(path), nil
+		}
+		return path, nil
 	}
-	dir = dir + "/.folderr/cli"
 	if os.Getenv("test") == "true" || os.Getenv("CI") == "true" {
 		var tempdir = os.TempDir()
 		var runner = os.Getenv("RUNNER_TEMP")
 		if len(runner) > 0 {
 			tempdir = runner
 		}
-		dir = os.Getenv(Constants.EnvPrefix + "CFG_TEMPDIR")
+		dir := os.Getenv(Constants.EnvPrefix + "CFG_TEMPDIR")
 		if dir == "" {
+			var err error
 			dir, err = os.MkdirTemp(tempdir, ".foldcli-")
 			if err != nil {
 				println("Failed to make temp dir for dry-run")
 				panic(err)
 			}
 		}
+		return dir, nil
+	}
+
+	home, homeErr := os.UserHomeDir()
+	legacy := ""
+	if homeErr == nil {
+		legacy = filepath.Join(home, ".folderr", "cli")
+	}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		dir := filepath.Join(xdg, Constants.RootCmdName)
+		if legacy != "" && !dry && !CheckIfDirExists(dir) && CheckIfDirExists(legacy) {
+			err := migrateLegacyConfigDir(legacy, dir)
+			if err != nil {
+				return "", err
+			}
+		}
+		return dir, nil
+	}
+
+	if homeErr != nil && dry {
+		println("Error accessing user directory:", homeErr)
+		println("This is a warning as you are in dry-run mode")
+	} else if homeErr != nil {
+		return "", fmt.Errorf(
+			"%w\nSet XDG_CONFIG_HOME, %vCONFIG or pass --config to choose where the config is kept",
+			homeErr,
+			Constants.EnvPrefix,
+		)
+	}
+	return legacy, nil
+}
 
+// Moves the config directory from $HOME/.folderr/cli to its XDG location
+func migrateLegacyConfigDir(legacy, dir string) error {
+	err := os.MkdirAll(filepath.Dir(dir), 0770)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(legacy, dir)
+	if err == nil {
+		fmt.Fprintf(os.Stderr, "Moved the config directory from %q to %q\n", legacy, dir)
+		return nil
+	}
+	// Renaming fails across filesystems, copy instead
+	err = CopyDir(legacy, dir)
+	if err != nil {
+		return fmt.Errorf("failed to move the config directory from %q to %q: %w", legacy, dir, err)
 	}
-	return dir, nil
+	fmt.Fprintf(os.Stderr, "Copied the config directory from %q to %q. You may remove the old one\n", legacy, dir)
+	return nil
 }
 
 type InitCheck struct {
@@ -81,13 +236,24 @@ func CheckInitialization(config *Config) InitCheck {
 	return initCheck
 }
 
+// Reads the config file in dir on its own, without the environment overrides ReadConfig applies.
+// Changes to the config file go through this, so temporary env values are never saved
+func ReadConfigFile(dir string) (*viper.Viper, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	v.SetConfigFile(ConfigFilePath(dir))
+	return v, v.ReadInConfig()
+}
+
 func ReadConfig(directory string, dry bool) (*viper.Viper, Config, SecretConfig, error) {
 	v := viper.New()
 	v.SetConfigType("yaml")
 	// config stuffs
 	dir := directory
+	path := ConfigFilePath(dir)
 	var err error
 	v.AddConfigPath(dir)
+	v.SetConfigFile(path)
 	err = v.ReadInConfig()
 	config := Config{}
 	secrets := SecretConfig{}
@@ -98,7 +264,7 @@ func ReadConfig(directory string, dry bool) (*viper.Viper, Config, SecretConfig,
 		println("Warning: Your config is not usable.")
 		println("Notice: No changes as in dry-run mode.")
 		println("Here's the error:", err.Error())
-	} else if err != nil && !strings.Contains(err.Error(), "Not Found") {
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return v, config, secrets, err
 	} else if err != nil {
 		err = os.MkdirAll(dir, 0770)
@@ -106,29 +272,83 @@ func ReadConfig(directory string, dry bool) (*viper.Viper, Config, SecretConfig,
 			return v, config, secrets, err
 		}
 
-		_, err = os.Create(dir + "/config.yaml")
+		_, err = os.Create(path)
 		if err != nil {
 			return v, config, secrets, err
 		}
+		v.Set(configVersionKey, CurrentConfigVersion)
 		err = v.WriteConfig()
 		if err != nil {
 			return v, config, secrets, err
 		}
-		return v, Config{}, secrets, nil
+		return v, Config{ConfigVersion: CurrentConfigVersion}, secrets, nil
+	} else {
+		_, err = migrateConfig(v, dry)
+		if err != nil {
+			return v, config, secrets, err
+		}
 	}
 
-	err = v.Unmarshal(&config)
+	if errs := ValidateConfig(v); len(errs) > 0 {
+		return v, config, secrets, &ConfigError{Path: v.ConfigFileUsed(), Errs: errs}
+	}
+
+	// Secret keys may be "file:" references. They are resolved in a copy of v so they never end up in the config file
+	resolved := viper.New()
+	err = resolved.MergeConfigMap(v.AllSettings())
 	if err != nil {
-		panic(err)
+		return v, config, secrets, err
+	}
+	for _, key := range ConfigKeys {
+		reference := v.GetString(key.Name)
+		if !key.Secret || !IsFileReference(reference) {
+			continue
+		}
+		value, err := ResolveSecretValue(reference)
+		if err != nil {
+			return v, config, secrets, fmt.Errorf("%v: %w", key.Name, err)
+		}
+		if _, err = key.Parse(value); err != nil {
+			return v, config, secrets, err
+		}
+		resolved.Set(key.Name, value)
+	}
+	err = resolved.Unmarshal(&config)
+	if err != nil {
+		return v, config, secrets, err
 	}
 
-	if dry {
-		if dbUrl := os.Getenv(Constants.EnvPrefix + "MONGO_URI"); dbUrl != "" {
-			config.Database.Url = dbUrl
-			v.Set("db.url", config.Database.Url)
+	// Secrets never go through v, so they can't end up in the config file
+	if SecretsExist(dir) {
+		key, ok, err := DefaultSecretsKey(dir)
+		if err != nil {
+			return v, config, secrets, err
+		}
+		if !ok {
+			println("Warning:", ErrSecretsLocked.Error())
+			secrets.Locked = true
+		} else {
+			stored, err := LoadSecrets(dir, key)
+			if err != nil {
+				return v, config, secrets, err
+			}
+			secrets.DBUrl = stored[SecretDBUrl]
+			secrets.GitToken = stored[SecretGitToken]
+			if secrets.DBUrl != "" {
+				config.Database.Url = secrets.DBUrl
+			}
 		}
 	}
 
+	envDbUrl, err := LookupSecretEnv(Constants.EnvPrefix + "MONGO_URI")
+	if err != nil {
+		return v, config, secrets, err
+	}
+	if dry && envDbUrl != "" {
+		config.Database.Url = envDbUrl
+		v.Set("db.url", config.Database.Url)
+	}
+
 	if os.Getenv(Constants.EnvPrefix+"DB_NAME") != "" {
 		config.Database.DbName = os.Getenv(Constants.EnvPrefix + "DB_NAME")
 		v.Set("db.dbName", config.Database.DbName)
@@ -155,19 +375,27 @@ func ReadConfig(directory string, dry bool) (*viper.Viper, Config, SecretConfig,
 		config.Repository = "https://github.com/Folderr/Folderr"
 		v.Set("repository", config.Repository)
 
-		if dbUrl := os.Getenv(Constants.EnvPrefix + "MONGO_URI"); dbUrl != "" {
-			config.Database.Url = dbUrl
+		if envDbUrl != "" {
+			config.Database.Url = envDbUrl
 			v.Set("db.url", config.Database.Url)
 		}
-		err = v.SafeWriteConfig()
+		if !v.IsSet(configVersionKey) {
+			config.ConfigVersion = CurrentConfigVersion
+			v.Set(configVersionKey, config.ConfigVersion)
+		}
+		err = v.SafeWriteConfigAs(path)
 		if err != nil {
 			fmt.Println("Tried working with temp directories. No luck.")
 			panic(err)
 		}
 	}
 
-	if GetGitToken() != "" {
-		secrets.GitToken = GetGitToken()
+	token, err := ResolveGitToken()
+	if err != nil {
+		return v, config, secrets, err
+	}
+	if token != "" {
+		secrets.GitToken = token
 	}
 	if config.Directory != "" && config.Repository != "" {
 		config.CanInstall = true
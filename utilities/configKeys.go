@@ -0,0 +1,434 @@
+package utilities
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type ConfigKeyType int
+
+const (
+	ConfigString ConfigKeyType = iota
+	ConfigBool
+	ConfigInt
+)
+
+func (t ConfigKeyType) String() string {
+	switch t {
+	case ConfigBool:
+		return "bool"
+	case ConfigInt:
+		return "int"
+	default:
+		return "string"
+	}
+}
+
+// A key the CLI persists in its config file.
+//
+// Name is the dotted viper key (i.e "db.url"), Env is the environment variable that overrides it (if any)
+// and Secret keys are redacted whenever they're displayed. ReadOnly keys are managed by the CLI itself.
+type ConfigKey struct {
+	Name        string
+	Type        ConfigKeyType
+	Description string
+	Env         string
+	Secret      bool
+	ReadOnly    bool
+	Default     interface{}
+	Allowed     []string
+	Validate    func(value string) error
+}
+
+const configVersionKey = "configVersion"
+
+// Where the value of a config key came from
+const (
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceSecrets = "secrets"
+	SourceDefault = "default"
+)
+
+var ConfigKeys = []ConfigKey{
+	{
+		Name:        configVersionKey,
+		Type:        ConfigInt,
+		Description: "Version of the config schema",
+		ReadOnly:    true,
+		Default:     0,
+		Validate: func(value string) error {
+			version, err := strconv.Atoi(value)
+			if err != nil {
+				return err
+			}
+			if version < 0 || version > CurrentConfigVersion {
+				return fmt.Errorf("unsupported config version %v, this CLI supports up to version %v", version, CurrentConfigVersion)
+			}
+			return nil
+		},
+	},
+	{
+		Name:        "directory",
+		Type:        ConfigString,
+		Description: "Directory Folderr is installed in",
+		Validate: func(value string) error {
+			if !IsValidPath(value) {
+				return fmt.Errorf("%q is not a valid path", value)
+			}
+			return nil
+		},
+	},
+	{
+		Name:        "repository",
+		Type:        ConfigString,
+		Description: "Git repository Folderr is installed from",
+		Validate: func(value string) error {
+			parsed, err := url.Parse(value)
+			if err != nil {
+				return err
+			}
+			if parsed.Scheme != "https" || parsed.Host == "" {
+				return fmt.Errorf("%q is not a HTTPS url", value)
+			}
+			return nil
+		},
+	},
+	{
+		Name:        "releaseType",
+		Type:        ConfigString,
+		Description: "Whether Folderr is updated from tags or commits",
+		Allowed:     []string{"tag", "commit"},
+	},
+	{
+		Name:        "release",
+		Type:        ConfigString,
+		Description: "The release (tag) of Folderr that is checked out",
+	},
+	{
+		Name:        "db.url",
+		Type:        ConfigString,
+		Description: "MongoDB connection URI",
+		Env:         Constants.EnvPrefix + "MONGO_URI",
+		Secret:      true,
+		Validate:    ValidateMongoURI,
+	},
+	{
+		Name:        "db.dbName",
+		Type:        ConfigString,
+		Description: "Name of the database Folderr uses",
+		Env:         Constants.EnvPrefix + "DB_NAME",
+	},
+	{
+		Name:        "db.tlsCAFile",
+		Type:        ConfigString,
+		Description: "PEM file of the certificate authorities to trust for TLS",
+		Validate:    validatePath,
+	},
+	{
+		Name:        "db.tlsCertFile",
+		Type:        ConfigString,
+		Description: "PEM file of the client certificate for TLS, can include the key",
+		Validate:    validatePath,
+	},
+	{
+		Name:        "db.tlsKeyFile",
+		Type:        ConfigString,
+		Description: "PEM file of the client certificate's key, if it isn't in db.tlsCertFile",
+		Validate:    validatePath,
+	},
+	{
+		Name:        "db.authSource",
+		Type:        ConfigString,
+		Description: "Database the user is authenticated against",
+	},
+	{
+		Name:        "db.authMechanism",
+		Type:        ConfigString,
+		Description: "How to authenticate with the database",
+		Allowed:     MongoAuthMechanisms,
+	},
+	{
+		Name:        "db.replicaSet",
+		Type:        ConfigString,
+		Description: "Name of the replica set to connect to",
+	},
+	{
+		Name:        "db.connectTimeout",
+		Type:        ConfigString,
+		Description: "How long to wait for a connection, like \"10s\"",
+		Validate:    validateDuration,
+	},
+	{
+		Name:        "db.serverSelectionTimeout",
+		Type:        ConfigString,
+		Description: "How long to wait for a usable server, like \"30s\"",
+		Validate:    validateDuration,
+	},
+	{
+		Name:        "db.appName",
+		Type:        ConfigString,
+		Description: "App name the CLI reports to the database",
+		Default:     DefaultMongoAppName,
+	},
+	{
+		Name:        "policy.passwordMinLength",
+		Type:        ConfigInt,
+		Description: "Fewest characters a password can have",
+		Default:     defaultPasswordMinLength,
+		Validate:    validatePositive,
+	},
+	{
+		Name:        "policy.passwordMaxLength",
+		Type:        ConfigInt,
+		Description: "Most characters a password can have",
+		Default:     defaultPasswordMaxLength,
+		Validate:    validatePositive,
+	},
+	{
+		Name:        "policy.passwordCharClasses",
+		Type:        ConfigString,
+		Description: "Characters passwords need one of each, from lower, upper, letter, digit & symbol",
+		Default:     defaultPasswordCharClasses,
+		Validate: func(value string) error {
+			_, err := ParseCharClasses(value)
+			return err
+		},
+	},
+	{
+		Name:        "policy.bannedPasswordsFile",
+		Type:        ConfigString,
+		Description: "File of passwords (one per line) that aren't allowed, on top of the built in list",
+		Validate: func(value string) error {
+			if !IsValidPath(value) {
+				return fmt.Errorf("%q is not a valid path", value)
+			}
+			return nil
+		},
+	},
+	{
+		Name:        "policy.usernameMinLength",
+		Type:        ConfigInt,
+		Description: "Fewest characters a username can have",
+		Default:     defaultUsernameMinLength,
+		Validate:    validatePositive,
+	},
+	{
+		Name:        "policy.usernameMaxLength",
+		Type:        ConfigInt,
+		Description: "Most characters a username can have",
+		Default:     defaultUsernameMaxLength,
+		Validate:    validatePositive,
+	},
+}
+
+func validatePath(value string) error {
+	if !IsValidPath(value) {
+		return fmt.Errorf("%q is not a valid path", value)
+	}
+	return nil
+}
+
+func validateDuration(value string) error {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if duration <= 0 {
+		return fmt.Errorf("must be more than 0, got %v", value)
+	}
+	return nil
+}
+
+func validatePositive(value string) error {
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+	if number < 1 {
+		return fmt.Errorf("must be at least 1, got %v", number)
+	}
+	return nil
+}
+
+// Finds a config key by name. Names are case-insensitive, like viper.
+func FindConfigKey(name string) (ConfigKey, bool) {
+	for _, key := range ConfigKeys {
+		if strings.EqualFold(key.Name, name) {
+			return key, true
+		}
+	}
+	return ConfigKey{}, false
+}
+
+// Returns the names of every known config key, sorted.
+func ConfigKeyNames() []string {
+	names := make([]string, 0, len(ConfigKeys))
+	for _, key := range ConfigKeys {
+		names = append(names, key.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parses a raw (command line) value into the type the key holds, and validates it.
+func (k ConfigKey) Parse(raw string) (interface{}, error) {
+	var value interface{}
+	switch k.Type {
+	case ConfigBool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%v must be a boolean (true/false), got %q", k.Name, raw)
+		}
+		value = parsed
+	case ConfigInt:
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%v must be a whole number, got %q", k.Name, raw)
+		}
+		value = parsed
+	default:
+		value = raw
+	}
+
+	if len(k.Allowed) > 0 {
+		allowed := false
+		for _, option := range k.Allowed {
+			if raw == option {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return nil, fmt.Errorf("%v must be one of %v, got %q", k.Name, strings.Join(k.Allowed, ", "), raw)
+		}
+	}
+
+	// Secrets read from files are validated once they've been read
+	if k.Secret && IsFileReference(raw) {
+		return value, nil
+	}
+	if k.Validate != nil {
+		if err := k.Validate(raw); err != nil {
+			return nil, fmt.Errorf("invalid value for %v: %w", k.Name, err)
+		}
+	}
+	return value, nil
+}
+
+// Reports where the value of the key in v (from ReadConfig) comes from: the environment, the config file or the default.
+//
+// The environment only counts when ReadConfig used it, i.e FOLDCLI_MONGO_URI is only applied in dry runs & tests
+func (k ConfigKey) Source(v *viper.Viper) string {
+	if k.Env != "" && v.IsSet(k.Name) {
+		value := os.Getenv(k.Env)
+		if k.Secret {
+			value, _ = LookupSecretEnv(k.Env)
+		}
+		if value != "" && fmt.Sprint(v.Get(k.Name)) == value {
+			return SourceEnv
+		}
+	}
+	if v.InConfig(k.Name) {
+		return SourceFile
+	}
+	return SourceDefault
+}
+
+// Formats a value for display, hiding anything secret.
+func (k ConfigKey) Display(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	str := fmt.Sprint(value)
+	if !k.Secret || str == "" || IsFileReference(str) {
+		return str
+	}
+	if parsed, err := url.Parse(str); err == nil && parsed.Scheme != "" && parsed.Host != "" {
+		return parsed.Redacted()
+	}
+	return "********"
+}
+
+// Validates the values of every known key set in v, and reports keys the CLI doesn't know.
+// Returns one error per bad key.
+func ValidateConfig(v *viper.Viper) []error {
+	errs := []error{}
+	unknown := []string{}
+	for _, name := range v.AllKeys() {
+		if _, ok := FindConfigKey(name); !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		errs = append(errs, fmt.Errorf("unknown key %q", name))
+	}
+
+	for _, key := range ConfigKeys {
+		if !v.IsSet(key.Name) {
+			continue
+		}
+		value := v.Get(key.Name)
+		if value == nil {
+			continue
+		}
+		// Empty strings are what "init" commands leave behind, and mean "not set"
+		if str, ok := value.(string); ok && str == "" {
+			continue
+		}
+		if _, err := key.Parse(fmt.Sprint(value)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Removes a key from v and writes the result to the config file v uses.
+// v should come from ReadConfigFile, everything in it is written.
+//
+// Viper has no way to unset a key so the config is rebuilt without it.
+func UnsetConfigKey(v *viper.Viper, name string) error {
+	settings := v.AllSettings()
+	path := strings.Split(strings.ToLower(name), ".")
+	parent := settings
+	for _, part := range path[:len(path)-1] {
+		next, ok := parent[part].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		parent = next
+	}
+	delete(parent, path[len(path)-1])
+
+	rebuilt := viper.New()
+	rebuilt.SetConfigType("yaml")
+	err := rebuilt.MergeConfigMap(settings)
+	if err != nil {
+		return err
+	}
+	return rebuilt.WriteConfigAs(v.ConfigFileUsed())
+}
+
+// Checks if the input is a MongoDB connection URI foldcli can work with
+func ValidateMongoURI(input string) error {
+	uri, err := url.Parse(input)
+	if err != nil {
+		return err
+	}
+	if (uri.Scheme != "mongodb" && uri.Scheme != "mongodb+srv") || uri.Host == "" {
+		return fmt.Errorf("expected an URI like \"mongodb://[user:password@]host/database\" or \"mongodb+srv://[user:password@]host/database\"")
+	}
+	// SRV records give the ports
+	if uri.Scheme == "mongodb+srv" && (uri.Port() != "" || strings.Contains(uri.Host, ",")) {
+		return fmt.Errorf("mongodb+srv URIs take one host name without a port")
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package utilities
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// The version of the config schema this build of the CLI reads and writes.
+// Bump it and add a migration to configMigrations whenever a persisted key is renamed, moved or removed.
+const CurrentConfigVersion = 1
+
+type configMigration struct {
+	// The version this migration upgrades from. It upgrades to From + 1
+	From        int
+	Description string
+	Migrate     func(settings map[string]interface{}) error
+}
+
+// Ordered list of migrations. configMigrations[i].From must be i
+var configMigrations = []configMigration{
+	{
+		From:        0,
+		Description: "Remove the computed \"CanInstall\" key",
+		Migrate: func(settings map[string]interface{}) error {
+			delete(settings, "caninstall")
+			return nil
+		},
+	},
+}
+
+// Reads the config version of the settings. Configs written before versioning are version 0
+func configVersionOf(settings map[string]interface{}) (int, error) {
+	raw, ok := settings[strings.ToLower(configVersionKey)]
+	if !ok || raw == nil {
+		return 0, nil
+	}
+	switch version := raw.(type) {
+	case int:
+		return version, nil
+	case int64:
+		return int(version), nil
+	case float64:
+		return int(version), nil
+	default:
+		return 0, fmt.Errorf("%v must be a whole number, got %q", configVersionKey, fmt.Sprint(raw))
+	}
+}
+
+// Upgrades settings to CurrentConfigVersion. Returns the version the settings were at.
+func MigrateConfigSettings(settings map[string]interface{}) (int, error) {
+	version, err := configVersionOf(settings)
+	if err != nil {
+		return 0, err
+	}
+	if version > CurrentConfigVersion {
+		return version, fmt.Errorf(
+			"config version %v is newer than this CLI supports (%v). Update %v",
+			version,
+			CurrentConfigVersion,
+			Constants.RootCmdName,
+		)
+	}
+	from := version
+	for version < CurrentConfigVersion {
+		migration := configMigrations[version]
+		err = migration.Migrate(settings)
+		if err != nil {
+			return from, fmt.Errorf("config migration from version %v (%v) failed: %w", version, migration.Description, err)
+		}
+		version++
+	}
+	settings[strings.ToLower(configVersionKey)] = CurrentConfigVersion
+	return from, nil
+}
+
+// Upgrades the config file v has read to CurrentConfigVersion, keeping a backup of the old file.
+// In dry-run mode only the in memory config is upgraded.
+//
+// Returns the version the file was at.
+func migrateConfig(v *viper.Viper, dry bool) (int, error) {
+	settings := v.AllSettings()
+	from, err := MigrateConfigSettings(settings)
+	if err != nil || from == CurrentConfigVersion {
+		return from, err
+	}
+
+	path := v.ConfigFileUsed()
+	backup := ""
+	// Empty configs have nothing worth backing up
+	if !dry && len(settings) > 1 {
+		original, err := os.ReadFile(path)
+		if err != nil {
+			return from, err
+		}
+		backup = fmt.Sprintf("%v.v%d-%v.bak", path, from, time.Now().Format("20060102150405"))
+		err = os.WriteFile(backup, original, 0600)
+		if err != nil {
+			return from, fmt.Errorf("failed to back up the config before migrating it: %w", err)
+		}
+	}
+
+	migrated, err := yaml.Marshal(settings)
+	if err != nil {
+		return from, err
+	}
+	if !dry {
+		err = os.WriteFile(path, migrated, 0600)
+		if err != nil {
+			return from, err
+		}
+		if backup != "" {
+			fmt.Fprintf(os.Stderr, "Upgraded config from version %v to %v. The old config was saved to %q\n", from, CurrentConfigVersion, backup)
+		}
+	}
+	// Swap what v has read for the migrated settings
+	return from, v.ReadConfig(bytes.NewReader(migrated))
+}
@@ -0,0 +1,171 @@
+package utilities
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// An index Folderr needs
+type IndexSpec struct {
+	Collection string
+	// Field & direction (1 or -1) pairs, in order
+	Keys   bson.D
+	Unique bool
+}
+
+// Named like the server names indexes, i.e "username_1"
+func (s IndexSpec) Name() string {
+	parts := []string{}
+	for _, key := range s.Keys {
+		parts = append(parts, fmt.Sprintf("%v_%v", key.Key, key.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+func (s IndexSpec) KeysString() string {
+	parts := []string{}
+	for _, key := range s.Keys {
+		parts = append(parts, fmt.Sprintf("%v: %v", key.Key, key.Value))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// The indexes Folderr's collections need
+var FolderrIndexes = []IndexSpec{
+	{Collection: "users", Keys: bson.D{{Key: "id", Value: 1}}, Unique: true},
+	{Collection: "users", Keys: bson.D{{Key: "username", Value: 1}}, Unique: true},
+	{Collection: "users", Keys: bson.D{{Key: "email", Value: 1}}, Unique: true},
+	{Collection: "files", Keys: bson.D{{Key: "id", Value: 1}}, Unique: true},
+	{Collection: "files", Keys: bson.D{{Key: "owner", Value: 1}}},
+	{Collection: "links", Keys: bson.D{{Key: "id", Value: 1}}, Unique: true},
+	{Collection: "links", Keys: bson.D{{Key: "owner", Value: 1}}},
+}
+
+// An index that exists on the database
+type ExistingIndex struct {
+	Collection string
+	Name       string
+	Keys       string
+	Unique     bool
+}
+
+// An index whose name matches a spec, but whose keys or uniqueness don't
+type IndexDrift struct {
+	Spec     IndexSpec
+	Existing ExistingIndex
+}
+
+type IndexDiff struct {
+	Missing []IndexSpec
+	Changed []IndexDrift
+	// Indexes Folderr doesn't need. The _id index is never extra
+	Extra []ExistingIndex
+}
+
+func (d IndexDiff) Empty() bool {
+	return len(d.Missing) == 0 && len(d.Changed) == 0 && len(d.Extra) == 0
+}
+
+func rawKeysString(keys bson.Raw) string {
+	elements, err := keys.Elements()
+	if err != nil {
+		return keys.String()
+	}
+	parts := []string{}
+	for _, element := range elements {
+		value := element.Value()
+		if number, ok := value.AsInt64OK(); ok {
+			parts = append(parts, fmt.Sprintf("%v: %v", element.Key(), number))
+		} else if number, ok := value.DoubleOK(); ok {
+			parts = append(parts, fmt.Sprintf("%v: %v", element.Key(), int64(number)))
+		} else {
+			parts = append(parts, fmt.Sprintf("%v: %v", element.Key(), value))
+		}
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// Lists the indexes of the given collections. Collections that don't exist have none
+func ListIndexes(ctx context.Context, db *mongo.Database, collections []string) ([]ExistingIndex, error) {
+	indexes := []ExistingIndex{}
+	for _, collection := range collections {
+		specs, err := db.Collection(collection).Indexes().ListSpecifications(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, spec := range specs {
+			indexes = append(indexes, ExistingIndex{
+				Collection: collection,
+				Name:       spec.Name,
+				Keys:       rawKeysString(spec.KeysDocument),
+				Unique:     spec.Unique != nil && *spec.Unique,
+			})
+		}
+	}
+	return indexes, nil
+}
+
+// The collections the specs are on, in order
+func IndexCollections(specs []IndexSpec) []string {
+	collections := []string{}
+	seen := map[string]bool{}
+	for _, spec := range specs {
+		if !seen[spec.Collection] {
+			seen[spec.Collection] = true
+			collections = append(collections, spec.Collection)
+		}
+	}
+	return collections
+}
+
+// Compares the indexes that exist against the specs
+func DiffIndexes(specs []IndexSpec, existing []ExistingIndex) IndexDiff {
+	diff := IndexDiff{}
+	byName := map[string]ExistingIndex{}
+	for _, index := range existing {
+		byName[index.Collection+"."+index.Name] = index
+	}
+	needed := map[string]bool{}
+	for _, spec := range specs {
+		needed[spec.Collection+"."+spec.Name()] = true
+		index, ok := byName[spec.Collection+"."+spec.Name()]
+		if !ok {
+			diff.Missing = append(diff.Missing, spec)
+		} else if index.Keys != spec.KeysString() || index.Unique != spec.Unique {
+			diff.Changed = append(diff.Changed, IndexDrift{Spec: spec, Existing: index})
+		}
+	}
+	for _, index := range existing {
+		if index.Name != "_id_" && !needed[index.Collection+"."+index.Name] {
+			diff.Extra = append(diff.Extra, index)
+		}
+	}
+	return diff
+}
+
+// Creates the indexes in specs that are missing. Indexes that have drifted are left alone.
+// Returns the created indexes and the diff from before creating them
+func EnsureIndexes(ctx context.Context, db *mongo.Database, specs []IndexSpec) ([]IndexSpec, IndexDiff, error) {
+	existing, err := ListIndexes(ctx, db, IndexCollections(specs))
+	if err != nil {
+		return nil, IndexDiff{}, err
+	}
+	diff := DiffIndexes(specs, existing)
+	created := []IndexSpec{}
+	for _, spec := range diff.Missing {
+		_, err = db.Collection(spec.Collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    spec.Keys,
+			Options: options.Index().SetName(spec.Name()).SetUnique(spec.Unique),
+		})
+		if err != nil {
+			return created, diff, fmt.Errorf("failed to create index %v on %v: %w", spec.Name(), spec.Collection, err)
+		}
+		created = append(created, spec)
+	}
+	return created, diff, nil
+}
@@ -0,0 +1,32 @@
+package utilities
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestDiffIndexes(t *testing.T) {
+	specs := []IndexSpec{
+		{Collection: "users", Keys: bson.D{{Key: "username", Value: 1}}, Unique: true},
+		{Collection: "users", Keys: bson.D{{Key: "email", Value: 1}}, Unique: true},
+		{Collection: "users", Keys: bson.D{{Key: "id", Value: 1}}, Unique: true},
+	}
+	existing := []ExistingIndex{
+		{Collection: "users", Name: "_id_", Keys: "{_id: 1}"},
+		{Collection: "users", Name: "username_1", Keys: "{username: 1}", Unique: true},
+		{Collection: "users", Name: "email_1", Keys: "{email: 1}"},
+		{Collection: "users", Name: "createdAt_-1", Keys: "{createdAt: -1}"},
+	}
+
+	diff := DiffIndexes(specs, existing)
+	if len(diff.Missing) != 1 || diff.Missing[0].Name() != "id_1" {
+		t.Errorf("Expected id_1 to be missing, got %+v", diff.Missing)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].Existing.Name != "email_1" {
+		t.Errorf("Expected email_1 to have drifted (not unique), got %+v", diff.Changed)
+	}
+	if len(diff.Extra) != 1 || diff.Extra[0].Name != "createdAt_-1" {
+		t.Errorf("Expected createdAt_-1 to be extra, got %+v", diff.Extra)
+	}
+}
@@ -0,0 +1,122 @@
+package utilities
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Records the steps of a multi-step change so they can be undone, newest first, if a later step fails
+type Journal struct {
+	steps []journalStep
+}
+
+type journalStep struct {
+	description string
+	undo        func() error
+}
+
+// A step that was undone, or failed to be
+type UndoneStep struct {
+	Description string
+	Err         error
+}
+
+// Records a completed step. description says what undo does, i.e "removed /path/to/key"
+func (j *Journal) Done(description string, undo func() error) {
+	if j == nil {
+		return
+	}
+	j.steps = append(j.steps, journalStep{description: description, undo: undo})
+}
+
+// Undoes every recorded step, newest first. Steps that fail to undo don't stop the rest
+func (j *Journal) Rollback() []UndoneStep {
+	if j == nil {
+		return nil
+	}
+	undone := []UndoneStep{}
+	for i := len(j.steps) - 1; i >= 0; i-- {
+		step := j.steps[i]
+		undone = append(undone, UndoneStep{Description: step.description, Err: step.undo()})
+	}
+	j.steps = nil
+	return undone
+}
+
+// Writes a file and records how to put back what was there before.
+// A nil journal just writes the file
+func (j *Journal) WriteFile(path string, contents []byte, perm fs.FileMode) error {
+	previous, err := os.ReadFile(path)
+	existed := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	var info fs.FileInfo
+	if existed {
+		info, err = os.Stat(path)
+		if err != nil {
+			return err
+		}
+	}
+	if err = os.WriteFile(path, contents, perm); err != nil {
+		return err
+	}
+	if existed {
+		j.Done("restored "+path, func() error {
+			return os.WriteFile(path, previous, info.Mode().Perm())
+		})
+	} else {
+		j.Done("removed "+path, func() error {
+			return os.Remove(path)
+		})
+	}
+	return nil
+}
+
+// Creates a directory (and its parents) and records how to remove the ones it created
+func (j *Journal) MkdirAll(path string, perm fs.FileMode) error {
+	missing := []string{}
+	for dir := path; ; {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		missing = append(missing, dir)
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	if err := os.MkdirAll(path, perm); err != nil {
+		return err
+	}
+	// Recorded shallowest first so they're undone deepest first, os.Remove only removes empty directories
+	for i := len(missing) - 1; i >= 0; i-- {
+		dir := missing[i]
+		j.Done("removed directory "+dir, func() error {
+			return os.Remove(dir)
+		})
+	}
+	return nil
+}
+
+// Formats undone steps for the user
+func DescribeRollback(undone []UndoneStep) string {
+	if len(undone) == 0 {
+		return "Nothing needed undoing"
+	}
+	description := "Undid the completed steps:"
+	for _, step := range undone {
+		if step.Err != nil {
+			description += fmt.Sprintf("\n  FAILED: %v (%v) - do this yourself", step.Description, step.Err)
+		} else {
+			description += "\n  " + step.Description
+		}
+	}
+	return description
+}
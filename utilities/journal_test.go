@@ -0,0 +1,47 @@
+package utilities
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournalRollback(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "locations.json")
+	err := os.WriteFile(existing, []byte(`{"keyConfigured": false}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	journal := &Journal{}
+	keysDir := filepath.Join(dir, "keys", "nested")
+	if err = journal.MkdirAll(keysDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err = journal.WriteFile(filepath.Join(keysDir, "privateJWT.pem"), []byte("key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = journal.WriteFile(existing, []byte(`{"keyConfigured": true}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	undone := journal.Rollback()
+	if len(undone) != 4 {
+		t.Errorf("Expected 4 undone steps, got %+v", undone)
+	}
+	for _, step := range undone {
+		if step.Err != nil {
+			t.Errorf("Failed to undo %v: %v", step.Description, step.Err)
+		}
+	}
+	if _, err = os.Stat(filepath.Join(dir, "keys")); !errors.Is(err, fs.ErrNotExist) {
+		t.Error("Expected the created directories to be removed")
+	}
+	contents, _ := os.ReadFile(existing)
+	if string(contents) != `{"keyConfigured": false}` {
+		t.Errorf("Expected locations.json to be restored, got %v", string(contents))
+	}
+}
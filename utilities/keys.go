@@ -1,47 +1,287 @@
 package utilities
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/pem"
+	"errors"
+	"fmt"
 )
 
+type KeyAlgorithm string
+
+const (
+	KeyRSA     KeyAlgorithm = "rsa"
+	KeyECDSA   KeyAlgorithm = "ecdsa"
+	KeyEd25519 KeyAlgorithm = "ed25519"
+)
+
+// How keys are encoded.
+//
+// pkcs1: "RSA PRIVATE KEY" & "RSA PUBLIC KEY", RSA only.
+// pkcs8: "PRIVATE KEY" & a PKIX "PUBLIC KEY".
+// pkix:  the traditional private key ("RSA PRIVATE KEY" or "EC PRIVATE KEY") & a PKIX "PUBLIC KEY".
+type KeyFormat string
+
+const (
+	KeyFormatPKCS1 KeyFormat = "pkcs1"
+	KeyFormatPKCS8 KeyFormat = "pkcs8"
+	KeyFormatPKIX  KeyFormat = "pkix"
+)
+
+var KeyAlgorithms = []string{string(KeyRSA), string(KeyECDSA), string(KeyEd25519)}
+var KeyFormats = []string{string(KeyFormatPKCS1), string(KeyFormatPKCS8), string(KeyFormatPKIX)}
+
+type KeyOptions struct {
+	Algorithm KeyAlgorithm
+	// RSA modulus size, or the ECDSA curve (256, 384 or 521). 0 is the default for the algorithm
+	Bits   int
+	Format KeyFormat
+}
+
+var DefaultKeyOptions = KeyOptions{Algorithm: KeyRSA, Bits: 2048, Format: KeyFormatPKCS8}
+
+var ecdsaCurves = map[int]elliptic.Curve{
+	256: elliptic.P256(),
+	384: elliptic.P384(),
+	521: elliptic.P521(),
+}
+
+// Fills in the default size for the algorithm
+func (o KeyOptions) withDefaults() KeyOptions {
+	if o.Bits == 0 {
+		switch o.Algorithm {
+		case KeyRSA:
+			o.Bits = 2048
+		case KeyECDSA:
+			o.Bits = 256
+		}
+	}
+	if o.Format == "" {
+		o.Format = KeyFormatPKCS8
+	}
+	return o
+}
+
+// Checks the options make keys node's crypto module can read
+func (o KeyOptions) Validate() error {
+	o = o.withDefaults()
+	switch o.Algorithm {
+	case KeyRSA:
+		// jsonwebtoken refuses RSA keys under 2048 bits
+		if o.Bits < 2048 || o.Bits > 8192 || o.Bits%8 != 0 {
+			return fmt.Errorf("RSA keys must be between 2048 and 8192 bits (a multiple of 8), got %v", o.Bits)
+		}
+	case KeyECDSA:
+		if _, ok := ecdsaCurves[o.Bits]; !ok {
+			return fmt.Errorf("ECDSA keys must be 256, 384 or 521 bits (P-256, P-384 or P-521), got %v", o.Bits)
+		}
+	case KeyEd25519:
+		if o.Bits != 0 {
+			return fmt.Errorf("Ed25519 keys have a fixed size, don't pass --bits")
+		}
+	default:
+		return fmt.Errorf("unknown algorithm %q, expected rsa, ecdsa or ed25519", o.Algorithm)
+	}
+	switch o.Format {
+	case KeyFormatPKCS1:
+		if o.Algorithm != KeyRSA {
+			return fmt.Errorf("the pkcs1 format is only for RSA keys, use pkcs8")
+		}
+	case KeyFormatPKIX:
+		if o.Algorithm == KeyEd25519 {
+			return fmt.Errorf("Ed25519 keys have no traditional format, use pkcs8")
+		}
+	case KeyFormatPKCS8:
+	default:
+		return fmt.Errorf("unknown format %q, expected pkcs1, pkcs8 or pkix", o.Format)
+	}
+	return nil
+}
+
+// Checks Folderr can sign logins with the keys. Its JWT library (jsonwebtoken) supports RS* & ES* but not EdDSA
+func (o KeyOptions) CheckFolderrCompatible() error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	if o.Algorithm == KeyEd25519 {
+		return fmt.Errorf("Folderr's JWT library can't sign with Ed25519 keys, use rsa or ecdsa")
+	}
+	return nil
+}
+
 // Generates public & private PEM encoded keys for Folderr's usage in its authentication handling.
 // Returns privateKey, publicKey, error
 func GenKeys() ([]byte, []byte, error) {
-	// generate keys
-	// this is for private keys
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	return GenKeysWithOptions(DefaultKeyOptions)
+}
+
+// Generates a PEM encoded key pair with the given algorithm, size & format.
+// Returns privateKey, publicKey, error
+func GenKeysWithOptions(options KeyOptions) ([]byte, []byte, error) {
+	if err := options.Validate(); err != nil {
+		return nil, nil, err
+	}
+	options = options.withDefaults()
+	var privateKey crypto.Signer
+	var err error
+	switch options.Algorithm {
+	case KeyRSA:
+		var rsaKey *rsa.PrivateKey
+		rsaKey, err = rsa.GenerateKey(rand.Reader, options.Bits)
+		if err == nil {
+			err = rsaKey.Validate()
+		}
+		privateKey = rsaKey
+	case KeyECDSA:
+		privateKey, err = ecdsa.GenerateKey(ecdsaCurves[options.Bits], rand.Reader)
+	case KeyEd25519:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	}
 	if err != nil {
 		return nil, nil, err
 	}
-	if privateKey.Validate() != nil {
-		return nil, nil, privateKey.Validate()
+
+	privBlock, err := encodePrivateKey(privateKey, options.Format)
+	if err != nil {
+		return nil, nil, err
 	}
-	// Turned to a PKCS8 key so Folderr's JWT library (node-jwt) can hopefully read it
-	privBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	pubBlock, err := encodePublicKey(privateKey.Public(), options.Format)
 	if err != nil {
 		return nil, nil, err
 	}
-	privBlock := pem.Block{
-		Type:    "RSA PRIVATE KEY",
-		Headers: nil,
-		Bytes:   privBytes,
+	return pem.EncodeToMemory(privBlock), pem.EncodeToMemory(pubBlock), nil
+}
+
+func encodePrivateKey(key crypto.Signer, format KeyFormat) (*pem.Block, error) {
+	if format == KeyFormatPKCS8 {
+		bytes, err := x509.MarshalPKCS8PrivateKey(key)
+		return &pem.Block{Type: "PRIVATE KEY", Bytes: bytes}, err
+	}
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}, nil
+	case *ecdsa.PrivateKey:
+		bytes, err := x509.MarshalECPrivateKey(key)
+		return &pem.Block{Type: "EC PRIVATE KEY", Bytes: bytes}, err
 	}
+	return nil, fmt.Errorf("can't encode %T as %v", key, format)
+}
 
-	privatePem := pem.EncodeToMemory(&privBlock)
+func encodePublicKey(key crypto.PublicKey, format KeyFormat) (*pem.Block, error) {
+	if rsaKey, ok := key.(*rsa.PublicKey); ok && format == KeyFormatPKCS1 {
+		return &pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(rsaKey)}, nil
+	}
+	bytes, err := x509.MarshalPKIXPublicKey(key)
+	return &pem.Block{Type: "PUBLIC KEY", Bytes: bytes}, err
+}
 
-	// We turn this key to a PKIX key so Folderr's JWT library (node-jwt) can hopefully read it
-	pubBytes := x509.MarshalPKCS1PublicKey(&privateKey.PublicKey)
+// Reads a PEM private key in any of the formats GenKeysWithOptions makes
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("not a PEM key")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		// Older CLIs labelled PKCS#8 keys as "RSA PRIVATE KEY"
+		if err != nil {
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		}
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unexpected PEM block %q, expected a private key", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
 
-	pubBlock := pem.Block{
-		Type:    "RSA PUBLIC KEY",
-		Headers: nil,
-		Bytes:   pubBytes,
+// Reads a PEM public key in any of the formats GenKeysWithOptions makes
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("not a PEM key")
 	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("unexpected PEM block %q, expected a public key", block.Type)
+}
 
-	publicPem := pem.EncodeToMemory(&pubBlock)
+// SHA-256 of the PKIX encoding of a public key, to tell keys apart
+func KeyFingerprint(key crypto.PublicKey) (string, error) {
+	bytes, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(bytes)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:]), nil
+}
+
+// Describes a key, i.e "RSA 2048" or "ECDSA P-256"
+func DescribeKey(key crypto.PublicKey) string {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %v", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + key.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	}
+	return fmt.Sprintf("%T", key)
+}
 
-	return privatePem, publicPem, nil
+// Signs a test payload with privateKey and verifies it with publicKey, like Folderr does with logins
+func VerifyKeyPair(privateKey crypto.Signer, publicKey crypto.PublicKey) error {
+	payload := []byte("foldcli key check")
+	digest := sha256.Sum256(payload)
+	switch publicKey := publicKey.(type) {
+	case *rsa.PublicKey:
+		signature, err := privateKey.Sign(rand.Reader, digest[:], crypto.SHA256)
+		if err != nil {
+			return err
+		}
+		return rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature)
+	case *ecdsa.PublicKey:
+		signature, err := privateKey.Sign(rand.Reader, digest[:], crypto.SHA256)
+		if err != nil {
+			return err
+		}
+		if !ecdsa.VerifyASN1(publicKey, digest[:], signature) {
+			return errors.New("signature check failed")
+		}
+		return nil
+	case ed25519.PublicKey:
+		signature, err := privateKey.Sign(rand.Reader, payload, crypto.Hash(0))
+		if err != nil {
+			return err
+		}
+		if !ed25519.Verify(publicKey, payload, signature) {
+			return errors.New("signature check failed")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported public key type %T", publicKey)
 }
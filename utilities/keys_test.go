@@ -0,0 +1,98 @@
+package utilities
+
+import (
+	"encoding/pem"
+	"testing"
+)
+
+func TestGenKeysWithOptions(t *testing.T) {
+	cases := []struct {
+		options     KeyOptions
+		privateType string
+		publicType  string
+	}{
+		{KeyOptions{Algorithm: KeyRSA, Format: KeyFormatPKCS1}, "RSA PRIVATE KEY", "RSA PUBLIC KEY"},
+		{KeyOptions{Algorithm: KeyRSA, Format: KeyFormatPKCS8}, "PRIVATE KEY", "PUBLIC KEY"},
+		{KeyOptions{Algorithm: KeyECDSA, Bits: 384, Format: KeyFormatPKIX}, "EC PRIVATE KEY", "PUBLIC KEY"},
+		{KeyOptions{Algorithm: KeyEd25519, Format: KeyFormatPKCS8}, "PRIVATE KEY", "PUBLIC KEY"},
+	}
+	for _, c := range cases {
+		privatePem, publicPem, err := GenKeysWithOptions(c.options)
+		if err != nil {
+			t.Errorf("%+v: %v", c.options, err)
+			continue
+		}
+		privateBlock, _ := pem.Decode(privatePem)
+		publicBlock, _ := pem.Decode(publicPem)
+		if privateBlock == nil || privateBlock.Type != c.privateType {
+			t.Errorf("%+v: expected a %q block", c.options, c.privateType)
+		}
+		if publicBlock == nil || publicBlock.Type != c.publicType {
+			t.Errorf("%+v: expected a %q block", c.options, c.publicType)
+		}
+	}
+}
+
+func TestKeyOptionsRejectsUnreadableKeys(t *testing.T) {
+	invalid := []KeyOptions{
+		{Algorithm: KeyRSA, Bits: 1024},
+		{Algorithm: KeyECDSA, Bits: 255},
+		{Algorithm: KeyECDSA, Format: KeyFormatPKCS1},
+		{Algorithm: KeyEd25519, Format: KeyFormatPKIX},
+		{Algorithm: KeyEd25519, Bits: 256},
+		{Algorithm: "dsa"},
+	}
+	for _, options := range invalid {
+		if options.Validate() == nil {
+			t.Errorf("Expected %+v to be rejected", options)
+		}
+	}
+	if (KeyOptions{Algorithm: KeyEd25519}).CheckFolderrCompatible() == nil {
+		t.Error("Expected Ed25519 to be rejected for Folderr")
+	}
+}
+
+func TestVerifyKeyPair(t *testing.T) {
+	for _, options := range []KeyOptions{DefaultKeyOptions, {Algorithm: KeyECDSA, Format: KeyFormatPKIX}, {Algorithm: KeyEd25519}} {
+		privatePem, publicPem, err := GenKeysWithOptions(options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		privateKey, err := ParsePrivateKeyPEM(privatePem)
+		if err != nil {
+			t.Fatal(err)
+		}
+		publicKey, err := ParsePublicKeyPEM(publicPem)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = VerifyKeyPair(privateKey, publicKey); err != nil {
+			t.Errorf("%+v: expected the pair to match, got %v", options, err)
+		}
+
+		_, otherPem, err := GenKeysWithOptions(options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		other, err := ParsePublicKeyPEM(otherPem)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if VerifyKeyPair(privateKey, other) == nil {
+			t.Errorf("%+v: expected keys from different pairs not to match", options)
+		}
+	}
+}
+
+func TestParseKeysFromOlderCLIs(t *testing.T) {
+	privatePem, _, err := GenKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Older CLIs wrote PKCS#8 keys labelled "RSA PRIVATE KEY"
+	block, _ := pem.Decode(privatePem)
+	block.Type = "RSA PRIVATE KEY"
+	if _, err = ParsePrivateKeyPEM(pem.EncodeToMemory(block)); err != nil {
+		t.Errorf("Expected mislabelled PKCS#8 keys to be read, got %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package utilities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collection that records which migrations ran on Folderr's database, and holds the migration lock
+const MigrationsCollection = "migrations"
+
+const migrationLockId = "lock"
+
+// Locks older than this are from runs that died, and get taken over
+const migrationLockTimeout = 30 * time.Minute
+
+var ErrMigrationLocked = errors.New("another migration is running. If it died, wait " + migrationLockTimeout.String() + " for its lock to expire")
+
+// A change to the schema of Folderr's database.
+// Down is nil for migrations that can't be undone
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+	Down        func(ctx context.Context, db *mongo.Database) error
+}
+
+// Every migration, in order. Versions start at 1 and never skip a number
+var Migrations = []Migration{
+	{
+		Version:     1,
+		Description: "Baseline schema created by \"setup db\" & \"setup owner\"",
+		Up:          func(ctx context.Context, db *mongo.Database) error { return nil },
+		Down:        func(ctx context.Context, db *mongo.Database) error { return nil },
+	},
+	{
+		// Older CLIs saved users with null arrays, Folderr expects empty ones
+		Version:     2,
+		Description: "Replace null cURLs & notifs of users with empty arrays",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			users := db.Collection("users")
+			for _, field := range []string{"cURLs", "notifs"} {
+				_, err := users.UpdateMany(ctx, bson.D{{Key: field, Value: nil}}, bson.D{
+					{Key: "$set", Value: bson.D{{Key: field, Value: bson.A{}}}},
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// A migration that ran on the database
+type AppliedMigration struct {
+	Version     int       `bson:"version"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"appliedAt"`
+}
+
+// One step of a migration plan
+type MigrationStep struct {
+	Migration Migration
+	// Whether the migration is undone instead of applied
+	Down bool
+}
+
+// The version of the newest migration
+func LatestMigrationVersion() int {
+	if len(Migrations) == 0 {
+		return 0
+	}
+	return Migrations[len(Migrations)-1].Version
+}
+
+// Lists the migrations that ran on db, oldest first
+func AppliedMigrations(ctx context.Context, db *mongo.Database) ([]AppliedMigration, error) {
+	cursor, err := db.Collection(MigrationsCollection).Find(
+		ctx,
+		bson.D{{Key: "version", Value: bson.D{{Key: "$exists", Value: true}}}},
+		options.Find().SetSort(bson.D{{Key: "version", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	applied := []AppliedMigration{}
+	err = cursor.All(ctx, &applied)
+	return applied, err
+}
+
+// The schema version of db, the newest migration that ran on it. 0 if none have
+func SchemaVersion(ctx context.Context, db *mongo.Database) (int, error) {
+	applied, err := AppliedMigrations(ctx, db)
+	if err != nil || len(applied) == 0 {
+		return 0, err
+	}
+	return applied[len(applied)-1].Version, nil
+}
+
+// Works out the steps that take the database from version current to version target
+func PlanMigrations(migrations []Migration, current int, target int) ([]MigrationStep, error) {
+	latest := 0
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+	if target < 0 || target > latest {
+		return nil, fmt.Errorf("there is no migration %v. Latest migration: %v", target, latest)
+	}
+	if current > latest {
+		return nil, fmt.Errorf("the database is at version %v, newer than this CLI knows (%v). Update the CLI", current, latest)
+	}
+	steps := []MigrationStep{}
+	if target >= current {
+		for _, migration := range migrations {
+			if migration.Version > current && migration.Version <= target {
+				steps = append(steps, MigrationStep{Migration: migration})
+			}
+		}
+		return steps, nil
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if migration.Version > target && migration.Version <= current {
+			if migration.Down == nil {
+				return nil, fmt.Errorf("migration %v (%v) can't be undone", migration.Version, migration.Description)
+			}
+			steps = append(steps, MigrationStep{Migration: migration, Down: true})
+		}
+	}
+	return steps, nil
+}
+
+// Runs a step of a migration plan and records it in the migrations collection
+func RunMigrationStep(ctx context.Context, db *mongo.Database, step MigrationStep) error {
+	coll := db.Collection(MigrationsCollection)
+	if step.Down {
+		err := step.Migration.Down(ctx, db)
+		if err != nil {
+			return err
+		}
+		_, err = coll.DeleteOne(ctx, bson.D{{Key: "version", Value: step.Migration.Version}})
+		return err
+	}
+	err := step.Migration.Up(ctx, db)
+	if err != nil {
+		return err
+	}
+	_, err = coll.InsertOne(ctx, AppliedMigration{
+		Version:     step.Migration.Version,
+		Description: step.Migration.Description,
+		AppliedAt:   time.Now(),
+	})
+	return err
+}
+
+// Takes the advisory migration lock, so two runs can't migrate at once.
+// Returns ErrMigrationLocked if someone else holds it. Call the returned func to release it
+func AcquireMigrationLock(ctx context.Context, db *mongo.Database) (func() error, error) {
+	coll := db.Collection(MigrationsCollection)
+	hostname, _ := os.Hostname()
+	// Mongo keeps milliseconds, truncated so the release below matches what was saved
+	now := time.Now().Truncate(time.Millisecond)
+	lock := bson.D{
+		{Key: "_id", Value: migrationLockId},
+		{Key: "holder", Value: fmt.Sprintf("%v:%v", hostname, os.Getpid())},
+		{Key: "lockedAt", Value: now},
+	}
+	_, err := coll.InsertOne(ctx, lock)
+	if mongo.IsDuplicateKeyError(err) {
+		// Take over expired locks
+		result, updateErr := coll.ReplaceOne(ctx, bson.D{
+			{Key: "_id", Value: migrationLockId},
+			{Key: "lockedAt", Value: bson.D{{Key: "$lt", Value: now.Add(-migrationLockTimeout)}}},
+		}, lock)
+		if updateErr != nil {
+			return nil, updateErr
+		}
+		if result.ModifiedCount == 0 {
+			return nil, ErrMigrationLocked
+		}
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		_, err := coll.DeleteOne(context.Background(), bson.D{
+			{Key: "_id", Value: migrationLockId},
+			{Key: "lockedAt", Value: now},
+		})
+		return err
+	}, nil
+}
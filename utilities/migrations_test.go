@@ -0,0 +1,37 @@
+package utilities
+
+import "testing"
+
+func TestPlanMigrations(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Up: Migrations[0].Up, Down: Migrations[0].Down},
+		{Version: 2, Up: Migrations[0].Up, Down: Migrations[0].Down},
+		{Version: 3, Up: Migrations[0].Up},
+	}
+
+	steps, err := PlanMigrations(migrations, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 2 || steps[0].Migration.Version != 2 || steps[1].Migration.Version != 3 || steps[0].Down {
+		t.Errorf("Expected migrations 2 & 3 to be applied, got %+v", steps)
+	}
+
+	steps, err = PlanMigrations(migrations, 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 2 || steps[0].Migration.Version != 2 || steps[1].Migration.Version != 1 || !steps[0].Down {
+		t.Errorf("Expected migrations 2 & 1 to be undone, got %+v", steps)
+	}
+
+	if _, err = PlanMigrations(migrations, 3, 2); err == nil {
+		t.Error("Expected undoing a migration without Down to fail")
+	}
+	if _, err = PlanMigrations(migrations, 0, 4); err == nil {
+		t.Error("Expected migrating past the latest migration to fail")
+	}
+	if _, err = PlanMigrations(migrations, 4, 3); err == nil {
+		t.Error("Expected databases newer than the CLI to be refused")
+	}
+}
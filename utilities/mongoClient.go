@@ -0,0 +1,211 @@
+package utilities
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// App name the CLI reports to MongoDB, unless db.appName is set
+const DefaultMongoAppName = "Folderr CLI"
+
+// Mechanisms the mongo driver can authenticate with
+var MongoAuthMechanisms = []string{"SCRAM-SHA-1", "SCRAM-SHA-256", "MONGODB-X509", "MONGODB-AWS", "GSSAPI", "PLAIN"}
+
+// Builds the client options for the database in db.
+//
+// timeout is used for the connect & server selection timeouts, unless db or the URI set them.
+// Options in db take priority over the same options in the URI. Errors wrap ErrInvalidMongoConfig.
+func MongoClientOptions(db DBConfig, timeout time.Duration) (*options.ClientOptions, error) {
+	opts, err := mongoClientOptions(db, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMongoConfig, err)
+	}
+	return opts, nil
+}
+
+func mongoClientOptions(db DBConfig, timeout time.Duration) (*options.ClientOptions, error) {
+	opts := options.Client().ApplyURI(db.Url)
+	// ApplyURI keeps its error for Validate
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid database URI: %w", err)
+	}
+	if db.TLSKeyFile != "" && db.TLSCertFile == "" {
+		return nil, fmt.Errorf("db.tlsKeyFile is set without db.tlsCertFile")
+	}
+
+	appName := DefaultMongoAppName
+	if db.AppName != "" {
+		appName = db.AppName
+	}
+	opts.SetAppName(appName)
+
+	connectTimeout, err := parseMongoTimeout("db.connectTimeout", db.ConnectTimeout, opts.ConnectTimeout, timeout)
+	if err != nil {
+		return nil, err
+	}
+	selectionTimeout, err := parseMongoTimeout("db.serverSelectionTimeout", db.ServerSelectionTimeout, opts.ServerSelectionTimeout, timeout)
+	if err != nil {
+		return nil, err
+	}
+	opts.SetConnectTimeout(connectTimeout)
+	opts.SetServerSelectionTimeout(selectionTimeout)
+
+	if db.ReplicaSet != "" {
+		opts.SetReplicaSet(db.ReplicaSet)
+	}
+
+	if db.AuthSource != "" || db.AuthMechanism != "" {
+		// Keep the username & password from the URI
+		credential := options.Credential{}
+		if opts.Auth != nil {
+			credential = *opts.Auth
+		}
+		if db.AuthSource != "" {
+			credential.AuthSource = db.AuthSource
+		}
+		if db.AuthMechanism != "" {
+			credential.AuthMechanism = db.AuthMechanism
+		}
+		opts.SetAuth(credential)
+	}
+
+	if db.TLSCAFile != "" || db.TLSCertFile != "" {
+		tlsConfig, err := mongoTLSConfig(db, opts.TLSConfig)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	if err = opts.Validate(); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// Connects to the database in db. This is how every command gets a client.
+func NewMongoClient(db DBConfig, timeout time.Duration) (*mongo.Client, error) {
+	opts, err := MongoClientOptions(db, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return mongo.Connect(context.TODO(), opts)
+}
+
+// Picks the timeout from the config, then the URI, then fallback
+func parseMongoTimeout(name string, value string, fromURI *time.Duration, fallback time.Duration) (time.Duration, error) {
+	if value == "" && fromURI != nil {
+		return *fromURI, nil
+	}
+	if value == "" {
+		return fallback, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for %v: %w", name, err)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("invalid value for %v: must be more than 0, got %v", name, value)
+	}
+	return timeout, nil
+}
+
+// Adds the CA & client certificate from db to the TLS config from the URI (if any)
+func mongoTLSConfig(db DBConfig, base *tls.Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if base != nil {
+		tlsConfig = base.Clone()
+	}
+
+	if db.TLSCAFile != "" {
+		pem, err := os.ReadFile(db.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in the CA file %q", db.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if db.TLSCertFile != "" {
+		// MongoDB usually keeps the certificate and key in one file
+		keyFile := db.TLSKeyFile
+		if keyFile == "" {
+			keyFile = db.TLSCertFile
+		}
+		cert, err := tls.LoadX509KeyPair(db.TLSCertFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// Steps of VerifyMongoDatabase, in order
+const (
+	MongoStepSettings    = "settings"
+	MongoStepPing        = "ping"
+	MongoStepCollections = "collections"
+)
+
+// Scratch collection VerifyMongoDatabase creates when it can't list collections. Dropped after
+const mongoVerifyCollection = "foldcli_verify"
+
+type MongoVerifyResult struct {
+	// The step that failed, or the last one that ran
+	Step string
+	// Collections in the database, if they could be listed
+	Collections int
+	// True when the collections couldn't be listed, but one could be created
+	Created bool
+}
+
+// Connects to the database in db, pings the server and checks that the database can be used.
+//
+// Listing collections is tried first. If that isn't allowed and canCreate is true, creating (then dropping)
+// a collection is tried instead. Errors are from the mongo driver, see ClassifyMongoError.
+func VerifyMongoDatabase(db DBConfig, timeout time.Duration, canCreate bool) (MongoVerifyResult, error) {
+	result := MongoVerifyResult{Step: MongoStepSettings}
+	client, err := NewMongoClient(db, timeout)
+	if err != nil {
+		return result, err
+	}
+	defer client.Disconnect(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*3)
+	defer cancel()
+
+	result.Step = MongoStepPing
+	if err = client.Ping(ctx, readpref.Primary()); err != nil {
+		return result, err
+	}
+
+	result.Step = MongoStepCollections
+	database := client.Database(db.DbName)
+	names, err := database.ListCollectionNames(ctx, bson.D{})
+	if err == nil {
+		result.Collections = len(names)
+		return result, nil
+	}
+	if !canCreate {
+		return result, err
+	}
+	if createErr := database.CreateCollection(ctx, mongoVerifyCollection); createErr != nil {
+		// Listing is what failed first, so that's the error to show
+		return result, err
+	}
+	result.Created = true
+	return result, database.Collection(mongoVerifyCollection).Drop(ctx)
+}
@@ -0,0 +1,137 @@
+package utilities
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MongoErrorKind int
+
+const (
+	MongoOK MongoErrorKind = iota
+	MongoTimeout
+	MongoNetwork
+	// Wrong username or password
+	MongoAuthentication
+	// Logged in, but not allowed to do that
+	MongoUnauthorized
+	MongoDuplicateKey
+	MongoNotFound
+	// The database settings can't be used, see ErrInvalidMongoConfig
+	MongoInvalidConfig
+	MongoUnknown
+)
+
+// Wrapped by the errors MongoClientOptions returns
+var ErrInvalidMongoConfig = errors.New("invalid database settings")
+
+func (k MongoErrorKind) String() string {
+	switch k {
+	case MongoOK:
+		return "ok"
+	case MongoTimeout:
+		return "timeout"
+	case MongoNetwork:
+		return "network error"
+	case MongoAuthentication:
+		return "authentication failed"
+	case MongoUnauthorized:
+		return "unauthorized"
+	case MongoDuplicateKey:
+		return "duplicate key"
+	case MongoNotFound:
+		return "not found"
+	case MongoInvalidConfig:
+		return "invalid settings"
+	default:
+		return "unknown error"
+	}
+}
+
+// MongoDB server error codes
+const (
+	mongoCodeUnauthorized         = 13
+	mongoCodeAuthenticationFailed = 18
+)
+
+func hasMongoErrorCode(err error, code int) bool {
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) && serverErr.HasErrorCode(code)
+}
+
+// Sorts errors from the mongo driver into the kinds of problems users can do something about
+func ClassifyMongoError(err error) MongoErrorKind {
+	if err == nil {
+		return MongoOK
+	}
+	if errors.Is(err, ErrInvalidMongoConfig) {
+		return MongoInvalidConfig
+	}
+	message := strings.ToLower(err.Error())
+	// Authentication errors are checked first, the driver can wrap them in network & timeout errors
+	switch {
+	case hasMongoErrorCode(err, mongoCodeAuthenticationFailed),
+		strings.Contains(message, "authentication failed"),
+		strings.Contains(message, "auth error"):
+		return MongoAuthentication
+	case hasMongoErrorCode(err, mongoCodeUnauthorized),
+		strings.Contains(message, "unauthorized"),
+		strings.Contains(message, "not authorized"):
+		return MongoUnauthorized
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return MongoNotFound
+	case mongo.IsTimeout(err):
+		return MongoTimeout
+	case mongo.IsNetworkError(err):
+		return MongoNetwork
+	case mongo.IsDuplicateKeyError(err):
+		return MongoDuplicateKey
+	}
+	return MongoUnknown
+}
+
+// Explains a mongo driver error to the user, with what they can do about it
+func DescribeMongoError(err error) string {
+	if err == nil {
+		return ""
+	}
+	before, after := describeMongoError(err)
+	return before + err.Error() + after
+}
+
+// Wraps a mongo driver error in DescribeMongoError's description, keeping it for errors.Is & ClassifyMongoError.
+// Returns nil for nil
+func WrapMongoError(err error) error {
+	if err == nil {
+		return nil
+	}
+	before, after := describeMongoError(err)
+	return fmt.Errorf("%v%w%v", before, err, after)
+}
+
+// The text DescribeMongoError puts before and after the error
+func describeMongoError(err error) (string, string) {
+	switch ClassifyMongoError(err) {
+	case MongoTimeout:
+		return "Server Timeout Error: ", "\nThis can mean that the server is offline, you're offline, or there is (at least) a firewall in the way"
+	case MongoNetwork:
+		return "Network Error: ", ""
+	case MongoAuthentication:
+		return "Authentication error. The username or password in your database URI is wrong, or the user belongs to another database (see authSource).\nError: ", ""
+	case MongoUnauthorized:
+		return "Authorization error. Please provide authentication information in the string, before the host.\n" +
+			"Alternatively the error could mean you do not have permissions on this database.\nError: ", ""
+	case MongoDuplicateKey:
+		return "Duplicate Error: a document with the same unique value already exists.\nError: ", ""
+	case MongoNotFound:
+		return "Not Found: ", ""
+	case MongoInvalidConfig:
+		return "Settings Error: ", "\nFix your database settings with \"" + Constants.RootCmdName + " init db --override\" or \"" + Constants.RootCmdName + " config set\""
+	default:
+		return "Encountered an unexpected database error\n" +
+			"Please submit issue with template \"bug report\" at https://github.com/Folderr/folderr-cli/issues with the error below\n", ""
+	}
+}
@@ -0,0 +1,25 @@
+package utilities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestWrapMongoError(t *testing.T) {
+	if WrapMongoError(nil) != nil {
+		t.Error("Expected nil to stay nil")
+	}
+	err := fmt.Errorf("%w: db.connectTimeout", ErrInvalidMongoConfig)
+	wrapped := WrapMongoError(err)
+	if wrapped.Error() != DescribeMongoError(err) {
+		t.Errorf("Expected the description as the message, got %q", wrapped.Error())
+	}
+	if !errors.Is(wrapped, ErrInvalidMongoConfig) || ClassifyMongoError(wrapped) != MongoInvalidConfig {
+		t.Errorf("Expected the wrapped error to still be an invalid config error, got %v", wrapped)
+	}
+	if !errors.Is(WrapMongoError(context.DeadlineExceeded), context.DeadlineExceeded) {
+		t.Error("Expected the wrapped error to still be a deadline error")
+	}
+}
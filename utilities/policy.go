@@ -0,0 +1,246 @@
+package utilities
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Kinds of characters a password can be required to contain
+type CharClass string
+
+const (
+	CharLower  CharClass = "lower"
+	CharUpper  CharClass = "upper"
+	CharLetter CharClass = "letter"
+	CharDigit  CharClass = "digit"
+	CharSymbol CharClass = "symbol"
+)
+
+var charClassDescriptions = map[CharClass]string{
+	CharLower:  "a lowercase letter",
+	CharUpper:  "an uppercase letter",
+	CharLetter: "a letter",
+	CharDigit:  "a number",
+	CharSymbol: "a symbol or punctuation (i.e ! or _)",
+}
+
+// Whether r belongs to the class
+func (c CharClass) Matches(r rune) bool {
+	switch c {
+	case CharLower:
+		return unicode.IsLower(r)
+	case CharUpper:
+		return unicode.IsUpper(r)
+	case CharLetter:
+		return unicode.IsLetter(r)
+	case CharDigit:
+		return unicode.IsDigit(r)
+	case CharSymbol:
+		return unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsMark(r)
+	}
+	return false
+}
+
+// Parses a comma separated list of character classes, i.e "letter,digit,symbol"
+func ParseCharClasses(list string) ([]CharClass, error) {
+	classes := []CharClass{}
+	for _, name := range strings.Split(list, ",") {
+		class := CharClass(strings.TrimSpace(strings.ToLower(name)))
+		if class == "" {
+			continue
+		}
+		if _, ok := charClassDescriptions[class]; !ok {
+			return nil, fmt.Errorf("unknown character class %q, expected lower, upper, letter, digit or symbol", class)
+		}
+		classes = append(classes, class)
+	}
+	return classes, nil
+}
+
+// Passwords everyone tries first. Extended with policy.bannedPasswordsFile
+var bannedPasswords = []string{
+	"password", "password1", "password1!", "passw0rd", "p@ssw0rd", "p@ssword1",
+	"12345678", "123456789", "1234567890", "qwerty123", "qwertyuiop", "1q2w3e4r",
+	"iloveyou", "letmein1", "welcome1", "welcome1!", "admin123", "admin123!",
+	"changeme", "changeme1", "folderr", "folderr1", "folderr1!", "folderr123",
+}
+
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+	// Classes of characters a password needs at least one of each
+	Classes []CharClass
+	// Lowercased passwords that aren't allowed
+	Banned map[string]bool
+}
+
+type UsernamePolicy struct {
+	MinLength int
+	MaxLength int
+}
+
+// The rules new usernames, emails & passwords must follow
+type Policy struct {
+	Password PasswordPolicy
+	Username UsernamePolicy
+}
+
+// Policy settings from the config. Zero values mean the default
+type PolicyConfig struct {
+	PasswordMinLength   int    `json:"passwordMinLength" mapstructure:"passwordMinLength"`
+	PasswordMaxLength   int    `json:"passwordMaxLength" mapstructure:"passwordMaxLength"`
+	PasswordCharClasses string `json:"passwordCharClasses" mapstructure:"passwordCharClasses"`
+	BannedPasswordsFile string `json:"bannedPasswordsFile" mapstructure:"bannedPasswordsFile"`
+	UsernameMinLength   int    `json:"usernameMinLength" mapstructure:"usernameMinLength"`
+	UsernameMaxLength   int    `json:"usernameMaxLength" mapstructure:"usernameMaxLength"`
+}
+
+const (
+	defaultPasswordMinLength   = 8
+	defaultPasswordMaxLength   = 256
+	defaultPasswordCharClasses = "letter,digit,symbol"
+	defaultUsernameMinLength   = 3
+	defaultUsernameMaxLength   = 16
+)
+
+func DefaultPolicy() Policy {
+	policy, _ := LoadPolicy(PolicyConfig{})
+	return policy
+}
+
+// Builds the policy from the config, reading the banned passwords file if there is one
+func LoadPolicy(config PolicyConfig) (Policy, error) {
+	orDefault := func(value int, fallback int) int {
+		if value <= 0 {
+			return fallback
+		}
+		return value
+	}
+	classList := config.PasswordCharClasses
+	if classList == "" {
+		classList = defaultPasswordCharClasses
+	}
+	classes, err := ParseCharClasses(classList)
+	if err != nil {
+		return Policy{}, err
+	}
+	policy := Policy{
+		Password: PasswordPolicy{
+			MinLength: orDefault(config.PasswordMinLength, defaultPasswordMinLength),
+			MaxLength: orDefault(config.PasswordMaxLength, defaultPasswordMaxLength),
+			Classes:   classes,
+			Banned:    map[string]bool{},
+		},
+		Username: UsernamePolicy{
+			MinLength: orDefault(config.UsernameMinLength, defaultUsernameMinLength),
+			MaxLength: orDefault(config.UsernameMaxLength, defaultUsernameMaxLength),
+		},
+	}
+	if policy.Password.MinLength > policy.Password.MaxLength {
+		return policy, fmt.Errorf("policy.passwordMinLength (%v) is more than policy.passwordMaxLength (%v)", policy.Password.MinLength, policy.Password.MaxLength)
+	}
+	if policy.Username.MinLength > policy.Username.MaxLength {
+		return policy, fmt.Errorf("policy.usernameMinLength (%v) is more than policy.usernameMaxLength (%v)", policy.Username.MinLength, policy.Username.MaxLength)
+	}
+	for _, password := range bannedPasswords {
+		policy.Password.Banned[password] = true
+	}
+	if config.BannedPasswordsFile == "" {
+		return policy, nil
+	}
+	file, err := os.Open(config.BannedPasswordsFile)
+	if err != nil {
+		return policy, fmt.Errorf("failed to read the banned passwords file: %w", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if password := strings.TrimSpace(scanner.Text()); password != "" {
+			policy.Password.Banned[strings.ToLower(password)] = true
+		}
+	}
+	return policy, scanner.Err()
+}
+
+// Every rule a value broke
+type PolicyError struct {
+	Field string
+	Errs  []error
+}
+
+func (e *PolicyError) Error() string {
+	lines := []string{e.Field + " is invalid:"}
+	for _, err := range e.Errs {
+		lines = append(lines, "  - "+err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func policyError(field string, errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &PolicyError{Field: field, Errs: errs}
+}
+
+// Describes the password rules, i.e for prompts
+func (p PasswordPolicy) Requirements() string {
+	requirements := []string{fmt.Sprintf("between %v and %v characters", p.MinLength, p.MaxLength)}
+	for _, class := range p.Classes {
+		requirements = append(requirements, "contain "+charClassDescriptions[class])
+	}
+	return "Passwords must be " + strings.Join(requirements, ", ")
+}
+
+// Checks a password against every rule. Returns a *PolicyError listing the broken ones
+func (p PasswordPolicy) Check(password string) error {
+	errs := []error{}
+	length := len([]rune(password))
+	if length < p.MinLength {
+		errs = append(errs, fmt.Errorf("must be at least %v characters", p.MinLength))
+	}
+	if length > p.MaxLength {
+		errs = append(errs, fmt.Errorf("must be at most %v characters", p.MaxLength))
+	}
+	for _, class := range p.Classes {
+		if strings.IndexFunc(password, class.Matches) == -1 {
+			errs = append(errs, errors.New("must contain "+charClassDescriptions[class]))
+		}
+	}
+	if p.Banned[strings.ToLower(password)] {
+		errs = append(errs, errors.New("is too common, choose another"))
+	}
+	return policyError("Password", errs)
+}
+
+// Usernames are letters, numbers and underscores
+func (p UsernamePolicy) Check(username string) error {
+	errs := []error{}
+	length := len([]rune(username))
+	if length < p.MinLength || length > p.MaxLength {
+		errs = append(errs, fmt.Errorf("must be between %v and %v characters", p.MinLength, p.MaxLength))
+	}
+	if strings.IndexFunc(username, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' }) != -1 {
+		errs = append(errs, errors.New("may only contain letters, numbers and underscores"))
+	}
+	return policyError("Username", errs)
+}
+
+// Checks that email is a bare address, i.e "admin@folderr.net" and not "Admin <admin@folderr.net>"
+func ValidateEmail(email string) error {
+	errs := []error{}
+	address, err := mail.ParseAddress(email)
+	if err != nil {
+		errs = append(errs, errors.New("must be an address like admin@folderr.net"))
+	} else if address.Address != email || address.Name != "" {
+		errs = append(errs, errors.New("must be only the address, without a name or brackets"))
+	} else if domain := email[strings.LastIndex(email, "@")+1:]; !strings.Contains(domain, ".") {
+		errs = append(errs, fmt.Errorf("domain %q has no top level domain (i.e .net)", domain))
+	}
+	return policyError("Email", errs)
+}
@@ -0,0 +1,64 @@
+package utilities
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPasswordPolicyReportsEveryRule(t *testing.T) {
+	policy := DefaultPolicy().Password
+	err := policy.Check("abc")
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("Expected a PolicyError, got %v", err)
+	}
+	// Too short, no number, no symbol
+	if len(policyErr.Errs) != 3 {
+		t.Errorf("Expected 3 broken rules, got %v", policyErr)
+	}
+	if err = policy.Check("c0rrect-horse"); err != nil {
+		t.Errorf("Expected a valid password, got %v", err)
+	}
+	if err = policy.Check("Password1!"); err == nil {
+		t.Error("Expected banned passwords to be refused")
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	banned := filepath.Join(t.TempDir(), "banned.txt")
+	err := os.WriteFile(banned, []byte("Hunter2-hunter2\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy, err := LoadPolicy(PolicyConfig{PasswordMinLength: 12, PasswordCharClasses: "upper,digit", BannedPasswordsFile: banned})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = policy.Password.Check("HUNTER2HUNTER2"); err != nil {
+		t.Errorf("Expected a valid password, got %v", err)
+	}
+	if err = policy.Password.Check("hunter2-hunter2"); err == nil {
+		t.Error("Expected passwords from the banned passwords file to be refused")
+	}
+	if err = policy.Password.Check("HUNTER2"); err == nil {
+		t.Error("Expected the configured minimum length to be used")
+	}
+	if _, err = LoadPolicy(PolicyConfig{PasswordCharClasses: "emoji"}); err == nil {
+		t.Error("Expected unknown character classes to be refused")
+	}
+}
+
+func TestValidateEmail(t *testing.T) {
+	for _, email := range []string{"admin@folderr.net", "first.last+tag@mail.example.org"} {
+		if err := ValidateEmail(email); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", email, err)
+		}
+	}
+	for _, email := range []string{"admin", "admin@localhost", "Admin <admin@folderr.net>", "@folderr.net"} {
+		if err := ValidateEmail(email); err == nil {
+			t.Errorf("Expected %q to be invalid", email)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package utilities
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// Config values starting with this are read from the file after it, i.e "file:/run/secrets/mongo"
+const FileReferencePrefix = "file:"
+
+func IsFileReference(value string) bool {
+	return strings.HasPrefix(value, FileReferencePrefix)
+}
+
+// Reads a secret from a file, like the ones Docker and systemd credentials provide.
+//
+// Refuses files that aren't regular files or that other users can write to, and warns about files anyone can read.
+// Trailing newlines are removed.
+func ReadSecretFile(path string) (string, error) {
+	if err := checkSecretFile(path, false); err != nil {
+		return "", err
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return strings.TrimRight(string(contents), "\r\n"), nil
+}
+
+// Checks that path is a regular file other users can't write to.
+// With private, other users can't read it either. Otherwise reading is only warned about
+func checkSecretFile(path string, private bool) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read secret file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("secret file %q is not a regular file", path)
+	}
+	// Windows doesn't have unix permissions, Go makes them up
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	perm := info.Mode().Perm()
+	if perm&0022 != 0 {
+		return fmt.Errorf("secret file %q can be written by other users (mode %v). Run \"chmod go-w %v\"", path, perm, path)
+	}
+	if private && perm&0044 != 0 {
+		return fmt.Errorf("secret file %q can be read by other users (mode %v). Run \"chmod go-rw %v\"", path, perm, path)
+	}
+	if perm&0004 != 0 {
+		fmt.Fprintf(os.Stderr, "Warning: secret file %q can be read by every user (mode %v)\n", path, perm)
+	}
+	return nil
+}
+
+// Resolves "file:" references. Any other value is returned as is
+func ResolveSecretValue(value string) (string, error) {
+	if !IsFileReference(value) {
+		return value, nil
+	}
+	return ReadSecretFile(strings.TrimPrefix(value, FileReferencePrefix))
+}
+
+// Looks up a secret in the environment: first the variable itself, then the file named by name + "_FILE".
+// The variable itself may also be a "file:" reference.
+func LookupSecretEnv(name string) (string, error) {
+	if value := os.Getenv(name); value != "" {
+		return ResolveSecretValue(value)
+	}
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		value, err := ReadSecretFile(path)
+		if err != nil {
+			return "", fmt.Errorf("%v_FILE: %w", name, err)
+		}
+		return value, nil
+	}
+	return "", nil
+}
+
+// Whether a secret is set in the environment, directly or through a file
+func HasSecretEnv(name string) bool {
+	return os.Getenv(name) != "" || os.Getenv(name+"_FILE") != ""
+}
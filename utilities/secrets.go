@@ -0,0 +1,224 @@
+package utilities
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Names of the secrets kept in the secrets file
+const (
+	SecretDBUrl    = "db.url"
+	SecretGitToken = "git.token"
+)
+
+var SecretNames = []string{SecretDBUrl, SecretGitToken}
+
+const secretsFileName = "secrets.enc"
+
+// Default location of the key file, in the config directory
+const SecretsKeyFileName = "secrets.key"
+
+const secretsVersion = 1
+
+// Bound to the ciphertext so a secrets file can't be passed off as something else
+var secretsAdditionalData = []byte("foldcli-secrets-v1")
+
+var ErrSecretsLocked = errors.New(
+	"the secrets file is locked. Set " + Constants.EnvPrefix + "SECRETS_PASSPHRASE(_FILE) or " +
+		Constants.EnvPrefix + "SECRETS_KEY_FILE, or put a key file at " + SecretsKeyFileName + " in the config directory",
+)
+var ErrSecretsWrongKey = errors.New("could not decrypt the secrets file. Wrong passphrase or key file")
+
+// What unlocks the secrets file. Either a passphrase or a key file
+type SecretsKey struct {
+	Passphrase string
+	KeyFile    string
+}
+
+func (k SecretsKey) material() ([]byte, error) {
+	if k.KeyFile != "" {
+		// Read as is, key files are raw bytes and may end in a newline
+		if err := checkSecretFile(k.KeyFile, true); err != nil {
+			return nil, err
+		}
+		contents, err := os.ReadFile(k.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the secrets key file: %w", err)
+		}
+		if len(contents) < 32 {
+			return nil, fmt.Errorf("the secrets key file %q is too short, it needs at least 32 bytes", k.KeyFile)
+		}
+		return contents, nil
+	}
+	if k.Passphrase == "" {
+		return nil, ErrSecretsLocked
+	}
+	return []byte(k.Passphrase), nil
+}
+
+// On-disk format of the secrets file.
+// The key is derived from the passphrase or key file with argon2id
+type secretsEnvelope struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	KeyFile bool   `json:"keyFile"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+func SecretsPath(dir string) string {
+	return filepath.Join(dir, secretsFileName)
+}
+
+func SecretsExist(dir string) bool {
+	_, err := os.Stat(SecretsPath(dir))
+	return err == nil
+}
+
+// Finds what unlocks the secrets file without asking the user.
+// Checks the SECRETS_KEY_FILE and SECRETS_PASSPHRASE(_FILE) env variables, then the key file in the config directory.
+func DefaultSecretsKey(dir string) (SecretsKey, bool, error) {
+	if keyFile := os.Getenv(Constants.EnvPrefix + "SECRETS_KEY_FILE"); keyFile != "" {
+		return SecretsKey{KeyFile: keyFile}, true, nil
+	}
+	passphrase, err := LookupSecretEnv(Constants.EnvPrefix + "SECRETS_PASSPHRASE")
+	if err != nil {
+		return SecretsKey{}, false, err
+	}
+	if passphrase != "" {
+		return SecretsKey{Passphrase: passphrase}, true, nil
+	}
+	keyFile := filepath.Join(dir, SecretsKeyFileName)
+	if _, err := os.Stat(keyFile); err == nil {
+		return SecretsKey{KeyFile: keyFile}, true, nil
+	}
+	return SecretsKey{}, false, nil
+}
+
+// Writes a new random key file for the secrets file
+func GenerateSecretsKeyFile(path string) error {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, key, 0600)
+}
+
+func secretsCipher(envelope secretsEnvelope, key SecretsKey) (cipher.AEAD, error) {
+	material, err := key.material()
+	if err != nil {
+		return nil, err
+	}
+	derived := argon2.IDKey(material, envelope.Salt, envelope.Time, envelope.Memory, envelope.Threads, 32)
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Decrypts the secrets file in dir. Returns an empty map if there is no secrets file
+func LoadSecrets(dir string, key SecretsKey) (map[string]string, error) {
+	secrets := map[string]string{}
+	contents, err := os.ReadFile(SecretsPath(dir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return secrets, nil
+	} else if err != nil {
+		return secrets, err
+	}
+
+	envelope := secretsEnvelope{}
+	err = json.Unmarshal(contents, &envelope)
+	if err != nil {
+		return secrets, fmt.Errorf("the secrets file %q is corrupt: %w", SecretsPath(dir), err)
+	}
+	if envelope.Version != secretsVersion || envelope.KDF != "argon2id" {
+		return secrets, fmt.Errorf("the secrets file %q uses an unsupported format (version %v)", SecretsPath(dir), envelope.Version)
+	}
+	if envelope.KeyFile && key.KeyFile == "" {
+		return secrets, fmt.Errorf("the secrets file is locked with a key file, not a passphrase: %w", ErrSecretsLocked)
+	}
+
+	aead, err := secretsCipher(envelope, key)
+	if err != nil {
+		return secrets, err
+	}
+	plain, err := aead.Open(nil, envelope.Nonce, envelope.Data, secretsAdditionalData)
+	if err != nil {
+		return secrets, ErrSecretsWrongKey
+	}
+	err = json.Unmarshal(plain, &secrets)
+	return secrets, err
+}
+
+// Encrypts secrets and writes them to the secrets file in dir, with a fresh salt and nonce
+func SaveSecrets(dir string, key SecretsKey, secrets map[string]string) error {
+	envelope := secretsEnvelope{
+		Version: secretsVersion,
+		KDF:     "argon2id",
+		KeyFile: key.KeyFile != "",
+		Time:    3,
+		Memory:  64 * 1024,
+		Threads: 4,
+		Salt:    make([]byte, 16),
+		Nonce:   nil,
+	}
+	_, err := rand.Read(envelope.Salt)
+	if err != nil {
+		return err
+	}
+	aead, err := secretsCipher(envelope, key)
+	if err != nil {
+		return err
+	}
+	envelope.Nonce = make([]byte, aead.NonceSize())
+	_, err = rand.Read(envelope.Nonce)
+	if err != nil {
+		return err
+	}
+
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	envelope.Data = aead.Seal(nil, envelope.Nonce, plain, secretsAdditionalData)
+	contents, err := json.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+	// Write then rename so a failed write never destroys the old secrets
+	temp := SecretsPath(dir) + ".tmp"
+	err = os.WriteFile(temp, contents, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(temp, SecretsPath(dir))
+}
+
+func IsSecretName(name string) bool {
+	for _, secret := range SecretNames {
+		if secret == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package utilities
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Parses a YAML document, keeping comments, key order and keys we don't know about.
+// Empty input gives an empty document.
+func ParseYAMLDocument(contents []byte) (*yaml.Node, error) {
+	doc := &yaml.Node{}
+	err := yaml.Unmarshal(contents, doc)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Kind == 0 {
+		doc.Kind = yaml.DocumentNode
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected the YAML document to be a map")
+	}
+	return doc, nil
+}
+
+func findYAMLKey(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// Returns the node at path (i.e "db", "url") in doc, or nil if it isn't there
+func GetYAMLNode(doc *yaml.Node, path ...string) *yaml.Node {
+	node := doc.Content[0]
+	for _, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		node = findYAMLKey(node, key)
+		if node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+// Sets the value at path in doc, creating maps along the way. Everything else in doc is left alone
+func SetYAMLValue(doc *yaml.Node, value interface{}, path ...string) error {
+	node := doc.Content[0]
+	for i, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("%q is not a map", path[i-1])
+		}
+		next := findYAMLKey(node, key)
+		if next == nil {
+			next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, next)
+		}
+		if i == len(path)-1 {
+			// Keep the comments of the old value
+			encoded := &yaml.Node{}
+			err := encoded.Encode(value)
+			if err != nil {
+				return err
+			}
+			encoded.HeadComment, encoded.LineComment, encoded.FootComment = next.HeadComment, next.LineComment, next.FootComment
+			*next = *encoded
+			return nil
+		}
+		node = next
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package utilities
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSetYAMLValueKeepsUnknownKeys(t *testing.T) {
+	original := `# Folderr's server config
+port: 8888 # the port Folderr listens on
+url: https://old.example.com
+signups: 2
+sentry:
+  dsn: https://sentry.example.com/1
+  tracing: false
+db:
+  # keep this in sync with the CLI
+  url: mongodb://127.0.0.1/folderr
+  poolSize: 10
+`
+	doc, err := ParseYAMLDocument([]byte(original))
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := []struct {
+		path  []string
+		value interface{}
+	}{
+		{[]string{"port"}, 9999},
+		{[]string{"url"}, "https://folderr.example.com"},
+		{[]string{"db", "url"}, "mongodb://db.example.com/folderr"},
+		{[]string{"db", "dbName"}, "folderr"},
+	}
+	for _, setting := range settings {
+		if err = SetYAMLValue(doc, setting.value, setting.path...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	output, err := yaml.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	written := string(output)
+	expected := []string{
+		"# Folderr's server config",
+		"port: 9999 # the port Folderr listens on",
+		"url: https://folderr.example.com",
+		"signups: 2",
+		"dsn: https://sentry.example.com/1",
+		"tracing: false",
+		"# keep this in sync with the CLI",
+		"url: mongodb://db.example.com/folderr",
+		"poolSize: 10",
+		"dbName: folderr",
+	}
+	for _, line := range expected {
+		if !strings.Contains(written, line) {
+			t.Errorf("Expected %q in the written config:\n%v", line, written)
+		}
+	}
+	// Unknown keys stay where they were
+	if strings.Index(written, "signups") > strings.Index(written, "sentry") {
+		t.Errorf("Keys were reordered:\n%v", written)
+	}
+
+	// And survive being read again
+	reread, err := ParseYAMLDocument(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node := GetYAMLNode(reread, "sentry", "tracing"); node == nil || node.Value != "false" {
+		t.Error("sentry.tracing was lost after reading the config again")
+	}
+}